@@ -0,0 +1,148 @@
+package diagnostic
+
+import (
+	"fmt"
+	"github.com/nesyuk/golox/scanner"
+	"strings"
+)
+
+type Severity uint8
+
+const (
+	ERROR Severity = iota
+	WARNING
+	NOTE
+)
+
+func (s Severity) String() string {
+	switch s {
+	case WARNING:
+		return "warning"
+	case NOTE:
+		return "note"
+	}
+	return "error"
+}
+
+// Span is a region of the source, lines and columns are 1-based and the end is exclusive.
+type Span struct {
+	Line, Column       int
+	EndLine, EndColumn int
+	Offset, EndOffset  int
+}
+
+func TokenSpan(t scanner.Token) Span {
+	return Span{t.Line, t.Column, t.EndLine, t.EndColumn, t.Offset, t.EndOffset}
+}
+
+// PointSpan is a zero-width span, used when only the position of the error is known.
+func PointSpan(line int, column int) Span {
+	return Span{Line: line, Column: column, EndLine: line, EndColumn: column}
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Span     Span
+	Message  string
+	Notes    []string
+}
+
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[1;31m"
+	colorYellow = "\x1b[1;33m"
+	colorCyan   = "\x1b[1;36m"
+	colorBlue   = "\x1b[1;34m"
+)
+
+// Renderer prints diagnostics together with the offending source line:
+//
+//	error: expect ')' after expression.
+//	 --> example.lox:1:13
+//	  |
+//	1 | print (3 + 2;
+//	  |             ^
+type Renderer struct {
+	filename string
+	lines    []string
+	color    bool
+}
+
+func NewRenderer(source string, filename string, color bool) *Renderer {
+	return &Renderer{filename, strings.Split(source, "\n"), color}
+}
+
+func (r *Renderer) Render(d Diagnostic) string {
+	var b strings.Builder
+	b.WriteString(r.paint(severityColor(d.Severity), d.Severity.String()))
+	b.WriteString(r.paint(colorBold, ": "+d.Message))
+	b.WriteString("\n")
+
+	hasLine := d.Span.Line > 0 && d.Span.Line <= len(r.lines)
+	lineNo := ""
+	if hasLine {
+		lineNo = fmt.Sprintf("%d", d.Span.Line)
+	}
+	gutter := strings.Repeat(" ", len(lineNo))
+
+	if d.Span.Line > 0 {
+		b.WriteString(fmt.Sprintf("%v%v %v:%d:%d\n", gutter, r.paint(colorBlue, "-->"), r.filename, d.Span.Line, d.Span.Column))
+	}
+	if hasLine {
+		line := strings.TrimRight(r.lines[d.Span.Line-1], "\r")
+		b.WriteString(fmt.Sprintf("%v %v\n", gutter, r.paint(colorBlue, "|")))
+		b.WriteString(fmt.Sprintf("%v %v %v\n", r.paint(colorBlue, lineNo), r.paint(colorBlue, "|"), line))
+		b.WriteString(fmt.Sprintf("%v %v %v\n", gutter, r.paint(colorBlue, "|"), r.paint(severityColor(d.Severity), underline(line, d.Span))))
+	}
+	for _, note := range d.Notes {
+		b.WriteString(fmt.Sprintf("%v %v %v\n", gutter, r.paint(colorBlue, "="), r.paint(colorBold, "note: ")+note))
+	}
+	return b.String()
+}
+
+func (r *Renderer) paint(color string, s string) string {
+	if !r.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func severityColor(s Severity) string {
+	switch s {
+	case WARNING:
+		return colorYellow
+	case NOTE:
+		return colorCyan
+	}
+	return colorRed
+}
+
+// underline builds the caret line, tabs are kept so the carets stay aligned with the source line.
+func underline(line string, span Span) string {
+	start := span.Column - 1
+	if start < 0 {
+		start = 0
+	}
+	if start > len(line) {
+		start = len(line)
+	}
+	end := len(line)
+	if span.EndLine == span.Line && span.EndColumn-1 < end {
+		end = span.EndColumn - 1
+	}
+
+	var b strings.Builder
+	for _, ch := range line[:start] {
+		if ch == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	b.WriteString("^")
+	if end-start > 1 {
+		b.WriteString(strings.Repeat("~", end-start-1))
+	}
+	return b.String()
+}
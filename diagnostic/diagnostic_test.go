@@ -0,0 +1,48 @@
+package diagnostic
+
+import (
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	source := "var a = 1;\nprint (a + 2;\n\tprint nope;"
+	tests := []struct {
+		d      Diagnostic
+		expect string
+	}{
+		{
+			Diagnostic{ERROR, Span{2, 13, 2, 14, 23, 24}, "expect ')' after expression.", nil},
+			"error: expect ')' after expression.\n --> test.lox:2:13\n  |\n2 | print (a + 2;\n  |             ^\n",
+		},
+		{
+			Diagnostic{WARNING, Span{3, 8, 3, 12, 32, 36}, "unused.", []string{"declared here."}},
+			"warning: unused.\n --> test.lox:3:8\n  |\n3 | \tprint nope;\n  | \t      ^~~~\n  = note: declared here.\n",
+		},
+		{
+			Diagnostic{ERROR, PointSpan(1, 1), "Unexpected character.", nil},
+			"error: Unexpected character.\n --> test.lox:1:1\n  |\n1 | var a = 1;\n  | ^\n",
+		},
+		{
+			Diagnostic{ERROR, Span{}, "no position.", nil},
+			"error: no position.\n",
+		},
+	}
+	for _, test := range tests {
+		got := NewRenderer(source, "test.lox", false).Render(test.d)
+		if got != test.expect {
+			t.Errorf("expect:\n%v\ngot:\n%v", test.expect, got)
+		}
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	got := NewRenderer("nil;", "test.lox", true).Render(Diagnostic{NOTE, Span{1, 1, 1, 4, 0, 3}, "msg", nil})
+	expect := "\x1b[1;36mnote\x1b[0m\x1b[1m: msg\x1b[0m\n" +
+		" \x1b[1;34m-->\x1b[0m test.lox:1:1\n" +
+		"  \x1b[1;34m|\x1b[0m\n" +
+		"\x1b[1;34m1\x1b[0m \x1b[1;34m|\x1b[0m nil;\n" +
+		"  \x1b[1;34m|\x1b[0m \x1b[1;36m^~~\x1b[0m\n"
+	if got != expect {
+		t.Errorf("expect: %q, got: %q", expect, got)
+	}
+}
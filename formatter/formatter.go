@@ -0,0 +1,650 @@
+package formatter
+
+import (
+	"fmt"
+	"github.com/nesyuk/golox/diagnostic"
+	"github.com/nesyuk/golox/interpreter"
+	"github.com/nesyuk/golox/parser"
+	"github.com/nesyuk/golox/scanner"
+	"github.com/nesyuk/golox/token"
+	"sort"
+	"strings"
+)
+
+const indentation = "  "
+
+// SyntaxError is returned by Format when the source can't be parsed.
+type SyntaxError struct {
+	Diagnostics []diagnostic.Diagnostic
+}
+
+func (e *SyntaxError) Error() string {
+	messages := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		messages = append(messages, fmt.Sprintf("[line %d:%d] %v", d.Span.Line, d.Span.Column, d.Message))
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Format returns the source in the canonical layout: one statement per line, two spaces of indentation,
+// single spaces around operators and opening braces on the line of their statement.
+// Comments are kept, a block comment inside a statement stays in place when the next token is on its line,
+// other comments inside a statement are moved after it.
+func Format(source string) (string, error) {
+	var diagnostics []diagnostic.Diagnostic
+	tokens := scanner.NewScanner(source, func(line int, column int, message string) {
+		diagnostics = append(diagnostics, diagnostic.Diagnostic{Severity: diagnostic.ERROR, Span: diagnostic.PointSpan(line, column), Message: message})
+	}).KeepComments().ScanTokens()
+	tokens, comments := splitComments(tokens)
+	statements, err := parser.NewParser(tokens, func(tok scanner.Token, message string) {
+		diagnostics = append(diagnostics, diagnostic.Diagnostic{Severity: diagnostic.ERROR, Span: diagnostic.TokenSpan(tok), Message: message})
+	}).Parse()
+	if len(diagnostics) != 0 {
+		return "", &SyntaxError{diagnostics}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	p := &printer{tokens: tokens, comments: comments, atBlockStart: true}
+	p.lines(len(statements), func(idx int) {
+		p.stmt(statements[idx])
+	})
+	return p.out.String(), nil
+}
+
+// splitComments separates the comments from the tokens of the parser.
+func splitComments(tokens []scanner.Token) ([]scanner.Token, []scanner.Token) {
+	code, comments := make([]scanner.Token, 0, len(tokens)), make([]scanner.Token, 0)
+	for _, tok := range tokens {
+		if tok.TokenType == scanner.COMMENT {
+			comments = append(comments, tok)
+		} else {
+			code = append(code, tok)
+		}
+	}
+	return code, comments
+}
+
+// printer writes statements in source order and follows along in the tokens,
+// so it knows what was written in the source but is not a part of the syntax tree:
+// comments, blank lines, the lexemes of literals and which while loops were for loops.
+type printer struct {
+	out    strings.Builder
+	tokens []scanner.Token
+	// Index of the first token that is not printed yet.
+	next     int
+	comments []scanner.Token
+	// Index of the first comment that is not printed yet.
+	comment int
+	indent  int
+	// Last source line printed, used to keep blank lines between statements.
+	lastLine     int
+	atBlockStart bool
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+// punct writes a keyword or a punctuation and skips it in the tokens.
+func (p *printer) punct(text string) {
+	next := p.tokens[p.next]
+	inSource := next.Lexeme != nil && *next.Lexeme == text
+	if inSource {
+		p.inlineComments(next, text)
+	}
+	p.write(text)
+	if inSource {
+		p.next++
+	}
+}
+
+// tok writes the lexeme of a token of the syntax tree.
+func (p *printer) tok(t *scanner.Token) {
+	p.inlineComments(*t, *t.Lexeme)
+	p.write(*t.Lexeme)
+	p.advance(t.Offset)
+}
+
+// advance marks the token at the offset and every token before it as printed.
+func (p *printer) advance(offset int) {
+	idx := sort.Search(len(p.tokens), func(i int) bool {
+		return p.tokens[i].Offset >= offset
+	})
+	if idx < len(p.tokens)-1 && p.tokens[idx].Offset == offset && idx >= p.next {
+		p.next = idx + 1
+	}
+}
+
+func (p *printer) peek() scanner.TokenType {
+	return p.tokens[p.next].TokenType
+}
+
+// pending reports whether a comment comes before the next token.
+func (p *printer) pending() bool {
+	return p.comment < len(p.comments) && p.comments[p.comment].Offset < p.tokens[p.next].Offset
+}
+
+// lines writes a line per item, with the comments before and after it.
+func (p *printer) lines(count int, line func(idx int)) {
+	for idx := 0; idx < count; idx++ {
+		p.leadingComments()
+		p.separate(p.tokens[p.next].Line)
+		p.write(strings.Repeat(indentation, p.indent))
+		line(idx)
+		p.lastLine = p.tokens[p.next-1].EndLine
+		p.trailingComment()
+		p.write("\n")
+	}
+	p.leadingComments()
+}
+
+// separate keeps one blank line where the source had any.
+func (p *printer) separate(line int) {
+	if !p.atBlockStart && line > p.lastLine+1 {
+		p.write("\n")
+	}
+	p.atBlockStart = false
+}
+
+func (p *printer) leadingComments() {
+	for p.pending() {
+		c := p.comments[p.comment]
+		p.separate(c.Line)
+		p.write(strings.Repeat(indentation, p.indent) + commentText(c) + "\n")
+		// A comment moved out of a statement comes before the end of the statement.
+		p.lastLine = max(p.lastLine, c.EndLine)
+		p.comment++
+	}
+}
+
+func (p *printer) trailingComment() {
+	if !p.pending() {
+		return
+	}
+	if c := p.comments[p.comment]; c.Line == p.lastLine {
+		p.write(" " + commentText(c))
+		p.lastLine = c.EndLine
+		p.comment++
+	}
+}
+
+// inlineComments writes the block comments that come right before the token on its line,
+// text is what is written for the token.
+func (p *printer) inlineComments(before scanner.Token, text string) {
+	for p.comment < len(p.comments) {
+		c := p.comments[p.comment]
+		if c.Offset > before.Offset || c.Line != before.Line || c.EndLine != before.Line || !strings.HasPrefix(*c.Lexeme, "/*") {
+			return
+		}
+		if out := p.out.String(); out != "" && !strings.ContainsAny(out[len(out)-1:], " \n([{") {
+			p.write(" ")
+		}
+		p.write(commentText(c))
+		if !strings.ContainsAny(text[:1], ")]},;.") {
+			p.write(" ")
+		}
+		p.comment++
+	}
+}
+
+func commentText(comment scanner.Token) string {
+	return strings.TrimRight(*comment.Lexeme, " \t\r")
+}
+
+func (p *printer) block(count int, line func(idx int)) {
+	p.punct("{")
+	if count == 0 && !p.pending() {
+		p.punct("}")
+		return
+	}
+	p.lastLine = p.tokens[p.next-1].EndLine
+	p.trailingComment()
+	p.write("\n")
+	p.indent++
+	p.atBlockStart = true
+	p.lines(count, line)
+	p.indent--
+	p.write(strings.Repeat(indentation, p.indent))
+	p.punct("}")
+}
+
+func (p *printer) statements(stmts []token.Stmt) {
+	p.block(len(stmts), func(idx int) {
+		p.stmt(stmts[idx])
+	})
+}
+
+// body writes the statement of an if or a loop.
+func (p *printer) body(stmt token.Stmt) {
+	p.write(" ")
+	p.stmt(stmt)
+}
+
+func (p *printer) commaSeparated(count int, item func(idx int)) {
+	for idx := 0; idx < count; idx++ {
+		if idx > 0 {
+			p.punct(",")
+			p.write(" ")
+		}
+		item(idx)
+	}
+	// A trailing comma is dropped.
+	if count > 0 && p.peek() == scanner.COMMA {
+		p.next++
+	}
+}
+
+func (p *printer) stmt(stmt token.Stmt) {
+	_, _ = stmt.Accept(p)
+}
+
+func (p *printer) expr(expr token.Expr) {
+	_, _ = expr.Accept(p)
+}
+
+func (p *printer) function(fn *token.FunctionStmt, lambda bool) {
+	p.tok(fn.Name)
+	if !fn.IsGetter {
+		if lambda {
+			p.write(" ")
+		}
+		p.punct("(")
+		p.commaSeparated(len(fn.Params), func(idx int) {
+			p.tok(fn.Params[idx])
+		})
+		p.punct(")")
+	}
+	p.write(" ")
+	p.statements(fn.Body)
+}
+
+// forLoop writes a while loop that the parser desugared from a for loop.
+func (p *printer) forLoop(initializer token.Stmt, loop *token.WhileStmt) {
+	p.punct("for")
+	p.write(" ")
+	p.punct("(")
+	if initializer != nil {
+		p.stmt(initializer)
+	} else {
+		p.punct(";")
+	}
+	// A missing condition is a literal true that is not in the source.
+	if p.peek() != scanner.SEMICOLON {
+		p.write(" ")
+		p.expr(loop.Condition)
+	}
+	p.punct(";")
+	if loop.Increment != nil {
+		p.write(" ")
+		p.expr(loop.Increment)
+	}
+	p.punct(")")
+	p.body(loop.Body)
+}
+
+func (p *printer) VisitBlockStmt(stmt *token.BlockStmt) (interface{}, error) {
+	if loop, ok := p.desugaredFor(stmt); ok {
+		p.forLoop(stmt.Statements[0], loop)
+		return nil, nil
+	}
+	p.statements(stmt.Statements)
+	return nil, nil
+}
+
+// desugaredFor returns the loop of a block that holds the initializer and the loop of a for statement.
+func (p *printer) desugaredFor(stmt *token.BlockStmt) (*token.WhileStmt, bool) {
+	if p.peek() != scanner.FOR || len(stmt.Statements) != 2 {
+		return nil, false
+	}
+	loop, ok := stmt.Statements[1].(*token.WhileStmt)
+	return loop, ok
+}
+
+func (p *printer) VisitBreakStmt(stmt *token.BreakStmt) (interface{}, error) {
+	p.tok(stmt.Keyword)
+	p.punct(";")
+	return nil, nil
+}
+
+func (p *printer) VisitContinueStmt(stmt *token.ContinueStmt) (interface{}, error) {
+	p.tok(stmt.Keyword)
+	p.punct(";")
+	return nil, nil
+}
+
+func (p *printer) VisitClassStmt(stmt *token.ClassStmt) (interface{}, error) {
+	p.punct("class")
+	p.write(" ")
+	p.tok(stmt.Name)
+	if stmt.Superclass != nil {
+		p.write(" ")
+		p.punct("<")
+		p.write(" ")
+		p.tok(&stmt.Superclass.Name)
+	}
+	p.write(" ")
+	// Methods and class methods are kept in the order of the source.
+	methods := append(append([]*token.FunctionStmt{}, stmt.Methods...), stmt.ClassMethods...)
+	sort.SliceStable(methods, func(i, j int) bool {
+		return methods[i].Name.Offset < methods[j].Name.Offset
+	})
+	p.block(len(methods), func(idx int) {
+		if p.peek() == scanner.CLASS {
+			p.punct("class")
+			p.write(" ")
+		}
+		p.function(methods[idx], false)
+	})
+	return nil, nil
+}
+
+func (p *printer) VisitExpressionStmt(stmt *token.ExpressionStmt) (interface{}, error) {
+	p.expr(stmt.Expression)
+	p.punct(";")
+	return nil, nil
+}
+
+func (p *printer) VisitFunctionStmt(stmt *token.FunctionStmt) (interface{}, error) {
+	p.punct("fun")
+	p.write(" ")
+	p.function(stmt, false)
+	return nil, nil
+}
+
+func (p *printer) VisitIfStmt(stmt *token.IfStmt) (interface{}, error) {
+	p.punct("if")
+	p.write(" ")
+	p.punct("(")
+	p.expr(stmt.Condition)
+	p.punct(")")
+	p.body(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		if strings.HasSuffix(p.out.String(), "}") {
+			p.write(" ")
+		} else {
+			p.write("\n" + strings.Repeat(indentation, p.indent))
+		}
+		p.punct("else")
+		p.body(stmt.ElseBranch)
+	}
+	return nil, nil
+}
+
+func (p *printer) VisitImportStmt(stmt *token.ImportStmt) (interface{}, error) {
+	p.tok(stmt.Keyword)
+	p.write(" ")
+	if stmt.Names != nil {
+		p.commaSeparated(len(stmt.Names), func(idx int) {
+			p.tok(stmt.Names[idx])
+		})
+		p.write(" ")
+		p.punct("from")
+		p.write(" ")
+	}
+	p.tok(stmt.Path)
+	p.punct(";")
+	return nil, nil
+}
+
+func (p *printer) VisitPrintStmt(stmt *token.PrintStmt) (interface{}, error) {
+	p.punct("print")
+	p.write(" ")
+	p.expr(stmt.Expression)
+	p.punct(";")
+	return nil, nil
+}
+
+func (p *printer) VisitReturnStmt(stmt *token.ReturnStmt) (interface{}, error) {
+	p.tok(stmt.Keyword)
+	if stmt.Value != nil {
+		p.write(" ")
+		p.expr(stmt.Value)
+	}
+	p.punct(";")
+	return nil, nil
+}
+
+func (p *printer) VisitThrowStmt(stmt *token.ThrowStmt) (interface{}, error) {
+	p.tok(stmt.Keyword)
+	p.write(" ")
+	p.expr(stmt.Value)
+	p.punct(";")
+	return nil, nil
+}
+
+func (p *printer) VisitTryStmt(stmt *token.TryStmt) (interface{}, error) {
+	p.tok(stmt.Keyword)
+	p.write(" ")
+	p.statements(stmt.Body)
+	if stmt.CatchName != nil {
+		p.write(" ")
+		p.punct("catch")
+		p.write(" ")
+		p.punct("(")
+		p.tok(stmt.CatchName)
+		p.punct(")")
+		p.write(" ")
+		p.statements(stmt.CatchBody)
+	}
+	if stmt.FinallyBody != nil {
+		p.write(" ")
+		p.punct("finally")
+		p.write(" ")
+		p.statements(stmt.FinallyBody)
+	}
+	return nil, nil
+}
+
+func (p *printer) VisitWhileStmt(stmt *token.WhileStmt) (interface{}, error) {
+	if p.peek() == scanner.FOR {
+		p.forLoop(nil, stmt)
+		return nil, nil
+	}
+	p.punct("while")
+	p.write(" ")
+	p.punct("(")
+	p.expr(stmt.Condition)
+	p.punct(")")
+	p.body(stmt.Body)
+	return nil, nil
+}
+
+func (p *printer) VisitVarStmt(stmt *token.VarStmt) (interface{}, error) {
+	p.punct("var")
+	p.write(" ")
+	p.tok(&stmt.Name)
+	if stmt.Initializer != nil {
+		p.write(" ")
+		p.punct("=")
+		p.write(" ")
+		p.expr(stmt.Initializer)
+	}
+	p.punct(";")
+	return nil, nil
+}
+
+func (p *printer) VisitAssignExpr(expr *token.AssignExpr) (interface{}, error) {
+	p.tok(&expr.Name)
+	p.write(" ")
+	p.punct("=")
+	p.write(" ")
+	p.expr(expr.Value)
+	return nil, nil
+}
+
+func (p *printer) VisitLiteralExpr(expr *token.LiteralExpr) (interface{}, error) {
+	switch p.peek() {
+	case scanner.NUMBER, scanner.STRING, scanner.TRUE, scanner.FALSE, scanner.NIL:
+		// Numbers keep the digits they were written with.
+		p.inlineComments(p.tokens[p.next], *p.tokens[p.next].Lexeme)
+		p.write(*p.tokens[p.next].Lexeme)
+		p.next++
+		return nil, nil
+	}
+	if s, ok := expr.Value.(string); ok {
+		p.write("\"" + s + "\"")
+	} else {
+		p.write(interpreter.Stringify(expr.Value))
+	}
+	return nil, nil
+}
+
+func (p *printer) VisitLogicalExpr(expr *token.LogicalExpr) (interface{}, error) {
+	p.expr(expr.Left)
+	p.write(" ")
+	p.tok(&expr.Operator)
+	p.write(" ")
+	p.expr(expr.Right)
+	return nil, nil
+}
+
+func (p *printer) VisitSetExpr(expr *token.SetExpr) (interface{}, error) {
+	p.expr(expr.Object)
+	p.punct(".")
+	p.tok(expr.Name)
+	p.write(" ")
+	p.punct("=")
+	p.write(" ")
+	p.expr(expr.Value)
+	return nil, nil
+}
+
+func (p *printer) VisitSuperExpr(expr *token.SuperExpr) (interface{}, error) {
+	p.tok(&expr.Keyword)
+	p.punct(".")
+	p.tok(&expr.Method)
+	return nil, nil
+}
+
+func (p *printer) VisitThisExpr(expr *token.ThisExpr) (interface{}, error) {
+	p.tok(&expr.Keyword)
+	return nil, nil
+}
+
+func (p *printer) VisitUnaryExpr(expr *token.UnaryExpr) (interface{}, error) {
+	p.tok(&expr.Operator)
+	p.expr(expr.Right)
+	return nil, nil
+}
+
+func (p *printer) VisitCallExpr(expr *token.CallExpr) (interface{}, error) {
+	p.expr(expr.Callee)
+	p.punct("(")
+	p.commaSeparated(len(expr.Arguments), func(idx int) {
+		p.expr(expr.Arguments[idx])
+	})
+	p.tok(expr.Paren)
+	return nil, nil
+}
+
+func (p *printer) VisitGetExpr(expr *token.GetExpr) (interface{}, error) {
+	p.expr(expr.Object)
+	p.punct(".")
+	p.tok(expr.Name)
+	return nil, nil
+}
+
+func (p *printer) VisitVariableExpr(expr *token.VariableExpr) (interface{}, error) {
+	p.tok(&expr.Name)
+	return nil, nil
+}
+
+func (p *printer) VisitBinaryExpr(expr *token.BinaryExpr) (interface{}, error) {
+	p.expr(expr.Left)
+	p.write(" ")
+	p.tok(&expr.Operator)
+	p.write(" ")
+	p.expr(expr.Right)
+	return nil, nil
+}
+
+func (p *printer) VisitGroupingExpr(expr *token.GroupingExpr) (interface{}, error) {
+	p.punct("(")
+	p.expr(expr.Expression)
+	p.punct(")")
+	return nil, nil
+}
+
+func (p *printer) VisitLambdaExpr(expr *token.LambdaExpr) (interface{}, error) {
+	p.function(expr.Function, true)
+	return nil, nil
+}
+
+func (p *printer) VisitListExpr(expr *token.ListExpr) (interface{}, error) {
+	p.tok(expr.Bracket)
+	p.commaSeparated(len(expr.Elements), func(idx int) {
+		p.expr(expr.Elements[idx])
+	})
+	p.punct("]")
+	return nil, nil
+}
+
+func (p *printer) VisitMapExpr(expr *token.MapExpr) (interface{}, error) {
+	p.tok(expr.Brace)
+	p.commaSeparated(len(expr.Keys), func(idx int) {
+		p.expr(expr.Keys[idx])
+		p.punct(":")
+		p.write(" ")
+		p.expr(expr.Values[idx])
+	})
+	p.punct("}")
+	return nil, nil
+}
+
+func (p *printer) VisitIndexExpr(expr *token.IndexExpr) (interface{}, error) {
+	p.expr(expr.Object)
+	p.punct("[")
+	p.expr(expr.Index)
+	p.tok(expr.Bracket)
+	return nil, nil
+}
+
+func (p *printer) VisitIndexSetExpr(expr *token.IndexSetExpr) (interface{}, error) {
+	p.expr(expr.Object)
+	p.punct("[")
+	p.expr(expr.Index)
+	p.tok(expr.Bracket)
+	p.write(" ")
+	p.punct("=")
+	p.write(" ")
+	p.expr(expr.Value)
+	return nil, nil
+}
+
+// VisitInterpolationExpr writes the string segments as they are in the source,
+// the parser drops the empty ones so they are taken from the tokens.
+func (p *printer) VisitInterpolationExpr(expr *token.InterpolationExpr) (interface{}, error) {
+	p.inlineComments(*expr.Start, "\"")
+	p.advance(expr.Start.Offset)
+	p.write("\"")
+	part := 0
+	segment := *expr.Start
+	for {
+		if text := segment.Literal.(string); text != "" {
+			p.write(text)
+			part++
+		}
+		if segment.TokenType == scanner.STRING {
+			break
+		}
+		p.write("${")
+		p.expr(expr.Parts[part])
+		part++
+		p.write("}")
+		for !p.segmentEnd() {
+			p.next++
+		}
+		segment = p.tokens[p.next]
+		p.next++
+	}
+	p.write("\"")
+	return nil, nil
+}
+
+// segmentEnd reports whether the next token is the segment that follows an interpolated expression.
+func (p *printer) segmentEnd() bool {
+	next := p.tokens[p.next]
+	return (next.TokenType == scanner.INTERPOLATION || next.TokenType == scanner.STRING) && strings.HasPrefix(*next.Lexeme, "}")
+}
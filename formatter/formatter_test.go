@@ -0,0 +1,116 @@
+package formatter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		source string
+		expect string
+	}{
+		{"var   x=1;print x+2 ;", "var x = 1;\nprint x + 2;\n"},
+		{"fun add(a,b){return a+b;}", "fun add(a, b) {\n  return a + b;\n}\n"},
+		{"{ var a = 1; { print -a; } }", "{\n  var a = 1;\n  {\n    print -a;\n  }\n}\n"},
+		{"if (a) { print 1; } else if (!a) print 2; else { print 3; }", "if (a) {\n  print 1;\n} else if (!a) print 2;\nelse {\n  print 3;\n}\n"},
+		{"while (a and b or c) a = a - 1;", "while (a and b or c) a = a - 1;\n"},
+		{"for(var i=0;i<3;i=i+1) print i;", "for (var i = 0; i < 3; i = i + 1) print i;\n"},
+		{"for(;;){break;}", "for (;;) {\n  break;\n}\n"},
+		{"for (i = 0; i < 2;) { continue; }", "for (i = 0; i < 2;) {\n  continue;\n}\n"},
+		{"{ var i = 0; for (; i < 2;) i = i + 1; }", "{\n  var i = 0;\n  for (; i < 2;) i = i + 1;\n}\n"},
+		{"class A<B{init(){this.x=1;}class make(){return A();}area{return super.area*2;}}",
+			"class A < B {\n  init() {\n    this.x = 1;\n  }\n  class make() {\n    return A();\n  }\n  area {\n    return super.area * 2;\n  }\n}\n"},
+		{"class Empty {}", "class Empty {}\n"},
+		{"var f = fun(a){return a;};print fun(){}();", "var f = fun (a) {\n  return a;\n};\nprint fun () {}();\n"},
+		{"var l = [1,2.50,\"a\",];var m = {\"a\":[],\"b\":nil,};", "var l = [1, 2.50, \"a\"];\nvar m = {\"a\": [], \"b\": nil};\n"},
+		{"l[0]=l[1];print m [\"a\"];", "l[0] = l[1];\nprint m[\"a\"];\n"},
+		{"print \"a ${x+1} b ${\"c\"}${ \"${1}\" }\";", "print \"a ${x + 1} b ${\"c\"}${\"${1}\"}\";\n"},
+		{"try{throw \"x\";}catch(e){print e;}finally{}", "try {\n  throw \"x\";\n} catch (e) {\n  print e;\n} finally {}\n"},
+		{"import  a,b from \"lib/x.lox\";import \"y.lox\";", "import a, b from \"lib/x.lox\";\nimport \"y.lox\";\n"},
+		{"print (1 + 2) * 3;", "print (1 + 2) * 3;\n"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		got, err := Format(test.source)
+		if err != nil {
+			t.Errorf("unexpected error: %v (in %q)", err, test.source)
+			continue
+		}
+		if got != test.expect {
+			t.Errorf("expect:\n%v\ngot:\n%v", test.expect, got)
+		}
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+	tests := []struct {
+		source string
+		expect string
+	}{
+		{"// header\n\nvar x = 1;   // trailing  \nprint x;", "// header\n\nvar x = 1; // trailing\nprint x;\n"},
+		{"fun f() { // opening\n  // first\n  return 1;\n\n\n  // last\n}", "fun f() { // opening\n  // first\n  return 1;\n\n  // last\n}\n"},
+		{"{\n  // only a comment\n}", "{\n  // only a comment\n}\n"},
+		{"print f(1,\n  // inside\n  2);\nprint 3;", "print f(1, 2);\n// inside\nprint 3;\n"},
+		{"class A {\n  a() {}\n\n  // b\n  b() {}\n}", "class A {\n  a() {}\n\n  // b\n  b() {}\n}\n"},
+		{"print 1;\n// end", "print 1;\n// end\n"},
+		{"print \"// not a comment\";", "print \"// not a comment\";\n"},
+		{"/* a\n /* nested */\n*/\nprint 1; /* b */\nprint 2 /* c */ + 3;", "/* a\n /* nested */\n*/\nprint 1; /* b */\nprint 2 /* c */ + 3;\n"},
+		{"m[\"a\"] = /* inline */ 3;\nprint f(/* none */);\nvar x = 1 /* one */;", "m[\"a\"] = /* inline */ 3;\nprint f(/* none */);\nvar x = 1 /* one */;\n"},
+		{"print 1 + /* a\n b */ 2;", "print 1 + 2;\n/* a\n b */\n"},
+	}
+	for _, test := range tests {
+		got, err := Format(test.source)
+		if err != nil {
+			t.Errorf("unexpected error: %v (in %q)", err, test.source)
+			continue
+		}
+		if got != test.expect {
+			t.Errorf("expect:\n%v\ngot:\n%v", test.expect, got)
+		}
+	}
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	_, err := Format("print (1;\nvar = 2;")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expect *SyntaxError, got: %v", err)
+	}
+	if expect := "[line 1:9] expect ')' after expression.\n[line 2:5] expect variable name"; err.Error() != expect {
+		t.Errorf("expect %q, got: %q", expect, err.Error())
+	}
+}
+
+// Formatting the example programs twice gives the same output as formatting them once.
+func TestFormatFiles(t *testing.T) {
+	paths, err := filepath.Glob("../files/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if filepath.Base(path) == "example.lox" {
+			// Tokens for the scanner, not a program.
+			continue
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, err := Format(string(source))
+		if err != nil {
+			t.Errorf("unexpected error: %v (in %v)", err, path)
+			continue
+		}
+		got, err := Format(formatted)
+		if err != nil {
+			t.Errorf("unexpected error: %v (in formatted %v)", err, path)
+			continue
+		}
+		if got != formatted {
+			t.Errorf("expect formatted %v not to change, got:\n%v", path, got)
+		}
+	}
+}
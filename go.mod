@@ -2,11 +2,15 @@ module github.com/nesyuk/golox
 
 go 1.22.4
 
-require golang.org/x/tools v0.22.0
+require (
+	golang.org/x/term v0.29.0
+	golang.org/x/tools v0.22.0
+)
 
 require (
 	golang.org/x/mod v0.18.0 // indirect
 	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
 )
 
 replace github.com/nesyuk/golox/scanner => ../scanner
@@ -12,6 +12,16 @@ type LoxCallable interface {
 	Call(*Interpreter, []interface{}) (interface{}, error)
 }
 
+// Object is a value with properties: instances and built-in values like lists.
+type Object interface {
+	Get(name *scanner.Token) (interface{}, error)
+}
+
+// settable is an object whose fields can be assigned: instances and classes.
+type settable interface {
+	Set(name *scanner.Token, value interface{})
+}
+
 type loxFunction struct {
 	declaration   *token.FunctionStmt
 	closure       *Environment
@@ -56,17 +66,23 @@ func (fn *loxFunction) Call(interpreter *Interpreter, arguments []interface{}) (
 }
 
 func (fn *loxFunction) String() string {
+	if fn.declaration.Name.TokenType == scanner.FUN {
+		return "<anonymous fn.>"
+	}
 	return fmt.Sprintf("<fn '%v'.>", *fn.declaration.Name.Lexeme)
 }
 
+// Classes are objects too: their properties are class fields and class methods.
 type loxClass struct {
-	name       string
-	superclass *loxClass
-	methods    map[string]*loxFunction
+	name         string
+	superclass   *loxClass
+	methods      map[string]*loxFunction
+	classMethods map[string]*loxFunction
+	fields       map[string]interface{}
 }
 
-func NewLoxClass(name string, superclass *loxClass, methods map[string]*loxFunction) LoxCallable {
-	return &loxClass{name, superclass, methods}
+func NewLoxClass(name string, superclass *loxClass, methods map[string]*loxFunction, classMethods map[string]*loxFunction) LoxCallable {
+	return &loxClass{name, superclass, methods, classMethods, make(map[string]interface{})}
 }
 
 func (cls *loxClass) findMethod(name string) *loxFunction {
@@ -79,6 +95,34 @@ func (cls *loxClass) findMethod(name string) *loxFunction {
 	return nil
 }
 
+func (cls *loxClass) findClassMethod(name string) *loxFunction {
+	if method, exist := cls.classMethods[name]; exist {
+		return method
+	}
+	if cls.superclass != nil {
+		return cls.superclass.findClassMethod(name)
+	}
+	return nil
+}
+
+func (cls *loxClass) Get(name *scanner.Token) (interface{}, error) {
+	if val, exist := cls.fields[*name.Lexeme]; exist {
+		return val, nil
+	}
+	// Class methods can't use 'this', so they are not bound.
+	if method := cls.findClassMethod(*name.Lexeme); method != nil {
+		return method, nil
+	}
+	return nil, &RuntimeError{
+		Token:   name,
+		Message: fmt.Sprintf("Undefined property '%v'.", *name.Lexeme),
+	}
+}
+
+func (cls *loxClass) Set(name *scanner.Token, value interface{}) {
+	cls.fields[*name.Lexeme] = value
+}
+
 func (cls *loxClass) Arity() int {
 	if initializer := cls.findMethod("init"); initializer != nil {
 		return initializer.Arity()
@@ -90,7 +134,7 @@ func (cls *loxClass) Call(interpreter *Interpreter, args []interface{}) (interfa
 	inst := NewLoxInstance(cls)
 	if initializer := cls.findMethod("init"); initializer != nil {
 		if _, err := initializer.bind(inst.(*loxInstance)).Call(interpreter, args); err != nil {
-			return nil, nil
+			return nil, err
 		}
 	}
 	return inst, nil
@@ -36,6 +36,15 @@ func (e *Environment) Get(name *scanner.Token) (interface{}, error) {
 	}
 }
 
+// globals returns the outermost environment: the globals of the module the code belongs to.
+func (e *Environment) globals() *Environment {
+	env := e
+	for env.enclosing != nil {
+		env = env.enclosing
+	}
+	return env
+}
+
 func (e *Environment) ancestor(distance int) *Environment {
 	env := e
 	for i := 0; i < distance; i++ {
@@ -56,7 +65,7 @@ func (e *Environment) Assign(name *scanner.Token, value interface{}) error {
 	if e.enclosing != nil {
 		return e.enclosing.Assign(name, value)
 	}
-	return &RuntimeError{name, fmt.Sprintf("Undefined variable '%v'", *name.Lexeme)}
+	return &RuntimeError{name, fmt.Sprintf("Undefined variable '%v'.", *name.Lexeme)}
 }
 
 func (e *Environment) AssignAt(distance int, name *scanner.Token, value interface{}) {
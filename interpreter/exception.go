@@ -0,0 +1,70 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nesyuk/golox/scanner"
+)
+
+// ThrowException unwinds the stack up to the closest catch clause.
+type ThrowException struct {
+	Value interface{}
+	// Keyword of the throw statement.
+	Token *scanner.Token
+}
+
+func (e *ThrowException) Error() string {
+	return fmt.Sprintf("Uncaught exception: %v", Stringify(e.Value))
+}
+
+// loxError is the value a catch clause receives for a runtime error.
+type loxError struct {
+	err *RuntimeError
+}
+
+func (e *loxError) Get(name *scanner.Token) (interface{}, error) {
+	switch *name.Lexeme {
+	case "message":
+		return e.err.Message, nil
+	case "line":
+		if e.err.Token == nil {
+			return nil, nil
+		}
+		return float64(e.err.Token.Line), nil
+	}
+	return nil, &RuntimeError{
+		Token:   name,
+		Message: fmt.Sprintf("Undefined property '%v'.", *name.Lexeme),
+	}
+}
+
+func (e *loxError) String() string {
+	return fmt.Sprintf("<error '%v'.>", e.err.Message)
+}
+
+// Catchable returns the value a catch clause receives for the error,
+// only thrown values and runtime errors can be caught.
+func Catchable(err error) (interface{}, bool) {
+	var throwErr *ThrowException
+	if errors.As(err, &throwErr) {
+		return throwErr.Value, true
+	}
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return &loxError{runtimeErr}, true
+	}
+	return nil, false
+}
+
+// Uncaught converts an exception that escaped every try statement to a runtime error,
+// a rethrown runtime error is reported as the original one.
+func Uncaught(err error) error {
+	var throwErr *ThrowException
+	if !errors.As(err, &throwErr) {
+		return err
+	}
+	if e, ok := throwErr.Value.(*loxError); ok {
+		return e.err
+	}
+	return &RuntimeError{throwErr.Token, throwErr.Error()}
+}
@@ -9,8 +9,8 @@ func (fn *clock) Arity() int {
 	return 0
 }
 
-func (fn *clock) Call(_ *Interpreter, _ []interface{}) interface{} {
-	return time.Now().UnixMilli() / 1000.0
+func (fn *clock) Call(_ *Interpreter, _ []interface{}) (interface{}, error) {
+	return float64(time.Now().UnixMilli()) / 1000.0, nil
 }
 
 func (fn *clock) String() string {
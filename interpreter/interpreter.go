@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/nesyuk/golox/scanner"
 	"github.com/nesyuk/golox/token"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -12,41 +14,140 @@ type Interpreter struct {
 	errorCallback ErrorCallback
 	printCallback PrintCallback
 	globals       *Environment
-	env           *Environment
-	locals        map[token.Expr]int
+	// The built-in library, shared by the program and its modules.
+	natives map[string]LoxCallable
+	env     *Environment
+	locals  map[token.Expr]int
+	loader  ModuleLoader
+	// Imported modules by their canonical path.
+	modules map[string]Object
+	// Paths of the program and of the modules being imported, innermost last.
+	importing []string
 }
 
 func New(onError ErrorCallback, onPrint PrintCallback) *Interpreter {
-	globals := NewEnvironment()
-	globals.Define("clock", clock{})
-	return &Interpreter{onError, onPrint, globals, globals, make(map[token.Expr]int, 0)}
+	globals, natives := NewEnvironment(), Natives()
+	for name, native := range natives {
+		globals.Define(name, native)
+	}
+	return &Interpreter{
+		errorCallback: onError,
+		printCallback: onPrint,
+		globals:       globals,
+		natives:       natives,
+		env:           globals,
+		locals:        make(map[token.Expr]int, 0),
+		modules:       make(map[string]Object),
+	}
 }
 
 func (i *Interpreter) Interpret(statements []token.Stmt) error {
-	for _, stmt := range statements {
-		_, err := i.exec(stmt)
+	_, err := i.Evaluate(statements)
 
-		var intErr *RuntimeError
-		if err != nil {
-			if !errors.As(err, &intErr) {
-				return err
-			}
-			i.errorCallback(err.(*RuntimeError))
-			return nil
+	var intErr *RuntimeError
+	if err != nil {
+		if !errors.As(err, &intErr) {
+			return err
 		}
+		i.errorCallback(intErr)
 	}
 	return nil
 }
 
-func stringify(value interface{}) string {
-	if value == nil {
+// Evaluate executes statements and returns runtime errors instead of reporting them.
+// The result is the value of the last statement when it is an expression statement.
+func (i *Interpreter) Evaluate(statements []token.Stmt) (interface{}, error) {
+	for idx, stmt := range statements {
+		if exprStmt, ok := stmt.(*token.ExpressionStmt); ok && idx == len(statements)-1 {
+			value, err := i.eval(exprStmt.Expression)
+			return value, Uncaught(err)
+		}
+		if _, err := i.exec(stmt); err != nil {
+			return nil, Uncaught(err)
+		}
+	}
+	return nil, nil
+}
+
+// Define binds a global variable.
+func (i *Interpreter) Define(name string, value interface{}) {
+	i.globals.Define(name, value)
+}
+
+// GlobalNames returns the names of the defined global variables.
+func (i *Interpreter) GlobalNames() []string {
+	names := make([]string, 0, len(i.globals.variables))
+	for name := range i.globals.variables {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Global returns the value of a global variable.
+func (i *Interpreter) Global(name string) (interface{}, bool) {
+	value, exist := i.globals.variables[name]
+	return value, exist
+}
+
+// Call invokes a Lox function, class or native with the given arguments.
+func (i *Interpreter) Call(callee LoxCallable, arguments []interface{}) (interface{}, error) {
+	if message := ArityError(callee, len(arguments)); message != "" {
+		return nil, &RuntimeError{Message: message}
+	}
+	result, err := callee.Call(i, arguments)
+	return result, Uncaught(err)
+}
+
+// Stringify formats a Lox value the way print shows it.
+func Stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
 		return "nil"
+	case float64:
+		return formatNumber(v)
 	}
-	str := fmt.Sprintf("%v", value)
-	if strings.HasSuffix(str, ".0") {
-		str = str[:2]
+	return fmt.Sprintf("%v", value)
+}
+
+// formatNumber prints integers without a fraction and other numbers with the fewest digits
+// that read back as the same number, very large and very small numbers use an exponent.
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "Infinity"
+	case math.IsInf(n, -1):
+		return "-Infinity"
+	}
+	if abs := math.Abs(n); abs != 0 && (abs >= 1e21 || abs < 1e-6) {
+		return strconv.FormatFloat(n, 'e', -1, 64)
 	}
-	return str
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// Add implements '+': numbers are added and strings are concatenated,
+// a number added to a string is stringified.
+func Add(operator *scanner.Token, left interface{}, right interface{}) (interface{}, error) {
+	switch l := left.(type) {
+	case float64:
+		switch r := right.(type) {
+		case float64:
+			return l + r, nil
+		case string:
+			return formatNumber(l) + r, nil
+		}
+		return nil, &RuntimeError{Token: operator, Message: fmt.Sprintf("Operands must be numbers: %v", Stringify(right))}
+	case string:
+		switch r := right.(type) {
+		case string:
+			return l + r, nil
+		case float64:
+			return l + formatNumber(r), nil
+		}
+		return nil, &RuntimeError{Token: operator, Message: fmt.Sprintf("Operands must be strings: %v", Stringify(right))}
+	}
+	return nil, &RuntimeError{Token: operator, Message: "Operands must be two numbers or two strings."}
 }
 
 func (i *Interpreter) Resolve(expr token.Expr, depth int) {
@@ -102,7 +203,7 @@ func (i *Interpreter) VisitAssignExpr(expr *token.AssignExpr) (interface{}, erro
 		i.env.AssignAt(distance, &expr.Name, value)
 		return nil, nil
 	}
-	if err := i.globals.Assign(&expr.Name, value); err != nil {
+	if err := i.env.globals().Assign(&expr.Name, value); err != nil {
 		return nil, err
 	}
 	return value, nil
@@ -113,7 +214,7 @@ func (i *Interpreter) VisitSetExpr(expr *token.SetExpr) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	inst, ok := obj.(*loxInstance)
+	inst, ok := obj.(settable)
 	if !ok {
 		return nil, &RuntimeError{
 			Token:   expr.Name,
@@ -156,12 +257,18 @@ func (i *Interpreter) VisitClassStmt(stmt *token.ClassStmt) (interface{}, error)
 		methods[*method.Name.Lexeme] = fn.(*loxFunction)
 	}
 
-	class := NewLoxClass(*stmt.Name.Lexeme, supercls, methods)
-
 	if stmt.Superclass != nil {
 		i.env = i.env.enclosing
 	}
 
+	// Class methods can't use 'super', so they close over the class declaration scope.
+	classMethods := make(map[string]*loxFunction, 0)
+	for _, method := range stmt.ClassMethods {
+		classMethods[*method.Name.Lexeme] = NewLoxFunction(method, i.env, false).(*loxFunction)
+	}
+
+	class := NewLoxClass(*stmt.Name.Lexeme, supercls, methods, classMethods)
+
 	if err := i.env.Assign(stmt.Name, class); err != nil {
 		return nil, err
 	}
@@ -173,14 +280,25 @@ func (i *Interpreter) VisitGetExpr(expr *token.GetExpr) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	inst, ok := obj.(*loxInstance)
-	if !ok {
-		return nil, &RuntimeError{
-			Token:   expr.Name,
-			Message: "Only instances have properties.",
+	if o, ok := obj.(Object); ok {
+		val, err := o.Get(expr.Name)
+		if err != nil {
+			return nil, err
 		}
+		return i.property(val)
+	}
+	return nil, &RuntimeError{
+		Token:   expr.Name,
+		Message: "Only instances have properties.",
+	}
+}
+
+// property runs a getter method, other property values are returned as is.
+func (i *Interpreter) property(val interface{}) (interface{}, error) {
+	if fn, ok := val.(*loxFunction); ok && fn.declaration.IsGetter {
+		return fn.Call(i, nil)
 	}
-	return inst.Get(expr.Name)
+	return val, nil
 }
 
 func (i *Interpreter) VisitSuperExpr(expr *token.SuperExpr) (interface{}, error) {
@@ -191,7 +309,7 @@ func (i *Interpreter) VisitSuperExpr(expr *token.SuperExpr) (interface{}, error)
 	if method == nil {
 		return nil, &RuntimeError{&expr.Method, fmt.Sprintf("Undefined property '%v'.", *expr.Method.Lexeme)}
 	}
-	return method.bind(instance), nil
+	return i.property(method.bind(instance))
 }
 
 func (i *Interpreter) VisitThisExpr(expr *token.ThisExpr) (interface{}, error) {
@@ -211,7 +329,7 @@ func (i *Interpreter) VisitPrintStmt(stmt *token.PrintStmt) (interface{}, error)
 	if err != nil {
 		return nil, err
 	}
-	i.printCallback(stringify(result))
+	i.printCallback(Stringify(result))
 	return nil, nil
 }
 
@@ -227,16 +345,60 @@ func (i *Interpreter) VisitReturnStmt(stmt *token.ReturnStmt) (interface{}, erro
 	return nil, &ReturnException{Value: value}
 }
 
+func (i *Interpreter) VisitThrowStmt(stmt *token.ThrowStmt) (interface{}, error) {
+	value, err := i.eval(stmt.Value)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &ThrowException{Value: value, Token: stmt.Keyword}
+}
+
+func (i *Interpreter) VisitTryStmt(stmt *token.TryStmt) (interface{}, error) {
+	_, err := i.execBlock(stmt.Body, NewScopeEnvironment(i.env))
+	if value, catchable := Catchable(err); catchable && stmt.CatchName != nil {
+		env := NewScopeEnvironment(i.env)
+		env.Define(*stmt.CatchName.Lexeme, value)
+		_, err = i.execBlock(stmt.CatchBody, env)
+	}
+	if stmt.FinallyBody != nil {
+		// An error, return or break of the finally clause replaces the pending one.
+		if _, finallyErr := i.execBlock(stmt.FinallyBody, NewScopeEnvironment(i.env)); finallyErr != nil {
+			return nil, finallyErr
+		}
+	}
+	return nil, err
+}
+
 func (i *Interpreter) VisitWhileStmt(stmt *token.WhileStmt) (interface{}, error) {
 	cond, err := i.eval(stmt.Condition)
 	for ; err == nil && i.isTruthy(cond); cond, err = i.eval(stmt.Condition) {
 		if _, err = i.exec(stmt.Body); err != nil {
-			return nil, err
+			var breakErr *BreakException
+			if errors.As(err, &breakErr) {
+				return nil, nil
+			}
+			var continueErr *ContinueException
+			if !errors.As(err, &continueErr) {
+				return nil, err
+			}
+		}
+		if stmt.Increment != nil {
+			if _, err = i.eval(stmt.Increment); err != nil {
+				return nil, err
+			}
 		}
 	}
 	return nil, err
 }
 
+func (i *Interpreter) VisitBreakStmt(_ *token.BreakStmt) (interface{}, error) {
+	return nil, &BreakException{}
+}
+
+func (i *Interpreter) VisitContinueStmt(_ *token.ContinueStmt) (interface{}, error) {
+	return nil, &ContinueException{}
+}
+
 func (i *Interpreter) VisitIfStmt(stmt *token.IfStmt) (interface{}, error) {
 	val, err := i.eval(stmt.Condition)
 	if err != nil {
@@ -323,24 +485,7 @@ func (i *Interpreter) VisitBinaryExpr(expr *token.BinaryExpr) (interface{}, erro
 		}
 		return left.(float64) * right.(float64), nil
 	case scanner.PLUS:
-		switch l := left.(type) {
-		case float64:
-			r, ok := right.(float64)
-			if ok {
-				return l + r, nil
-			} else {
-				return nil, &RuntimeError{Token: &expr.Operator, Message: fmt.Sprintf("Operands must be numbers: %v", right)}
-			}
-		case string:
-			r, ok := right.(string)
-			if ok {
-				return l + r, nil
-			} else {
-				return nil, &RuntimeError{Token: &expr.Operator, Message: fmt.Sprintf("Operands must be strings: %v", right)}
-			}
-		default:
-			return nil, &RuntimeError{Token: &expr.Operator, Message: "Operands must be two numbers or two strings."}
-		}
+		return Add(&expr.Operator, left, right)
 	case scanner.GREATER:
 		if err := checkNumberOperands(expr.Operator, left, right); err != nil {
 			return nil, err
@@ -387,19 +532,99 @@ func (i *Interpreter) VisitCallExpr(expr *token.CallExpr) (interface{}, error) {
 	if !ok {
 		return nil, &RuntimeError{Token: expr.Paren, Message: "Can only call functions and classes."}
 	}
-	if len(args) != function.Arity() {
-		return nil, &RuntimeError{
-			Token:   expr.Paren,
-			Message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(args)),
+	if message := ArityError(function, len(args)); message != "" {
+		return nil, &RuntimeError{Token: expr.Paren, Message: message}
+	}
+	result, err := function.Call(i, args)
+	if _, isNative := function.(*nativeFunction); isNative && err != nil {
+		// Errors of Go functions are reported at the call site,
+		// exceptions thrown by Lox code called back from Go keep unwinding.
+		if _, catchable := Catchable(err); !catchable {
+			return nil, &RuntimeError{Token: expr.Paren, Message: err.Error()}
 		}
 	}
-	return function.Call(i, args)
+	return result, err
 }
 
 func (i *Interpreter) VisitGroupingExpr(expr *token.GroupingExpr) (interface{}, error) {
 	return i.eval(expr.Expression)
 }
 
+func (i *Interpreter) VisitLambdaExpr(expr *token.LambdaExpr) (interface{}, error) {
+	return NewLoxFunction(expr.Function, i.env, false), nil
+}
+
+func (i *Interpreter) VisitListExpr(expr *token.ListExpr) (interface{}, error) {
+	elements := make([]interface{}, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		value, err := i.eval(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+	}
+	return NewList(elements), nil
+}
+
+func (i *Interpreter) VisitInterpolationExpr(expr *token.InterpolationExpr) (interface{}, error) {
+	var sb strings.Builder
+	for _, part := range expr.Parts {
+		value, err := i.eval(part)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(Stringify(value))
+	}
+	return sb.String(), nil
+}
+
+func (i *Interpreter) VisitMapExpr(expr *token.MapExpr) (interface{}, error) {
+	keys, values := make([]interface{}, 0, len(expr.Keys)), make([]interface{}, 0, len(expr.Values))
+	for idx := range expr.Keys {
+		key, err := i.eval(expr.Keys[idx])
+		if err != nil {
+			return nil, err
+		}
+		value, err := i.eval(expr.Values[idx])
+		if err != nil {
+			return nil, err
+		}
+		keys, values = append(keys, key), append(values, value)
+	}
+	return NewMap(expr.Brace, keys, values)
+}
+
+func (i *Interpreter) VisitIndexExpr(expr *token.IndexExpr) (interface{}, error) {
+	obj, err := i.eval(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.eval(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	return GetIndex(obj, expr.Bracket, index)
+}
+
+func (i *Interpreter) VisitIndexSetExpr(expr *token.IndexSetExpr) (interface{}, error) {
+	obj, err := i.eval(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.eval(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.eval(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := SetIndex(obj, expr.Bracket, index, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
 func (i *Interpreter) VisitVariableExpr(expr *token.VariableExpr) (interface{}, error) {
 	return i.lookupVariable(&expr.Name, expr)
 }
@@ -408,8 +633,7 @@ func (i *Interpreter) lookupVariable(name *scanner.Token, expr token.Expr) (inte
 	if distance, exist := i.locals[expr]; exist {
 		return i.env.GetAt(distance, *name.Lexeme), nil
 	}
-	val, _ := i.globals.Get(name)
-	return val, nil
+	return i.env.globals().Get(name)
 }
 
 func (i *Interpreter) isTruthy(value interface{}) bool {
@@ -484,4 +708,19 @@ func (e *ReturnException) Error() string {
 	return fmt.Sprintf("%v", e.Value)
 }
 
+// BreakException and ContinueException unwind the statements of a loop body.
+type BreakException struct {
+}
+
+func (e *BreakException) Error() string {
+	return "break"
+}
+
+type ContinueException struct {
+}
+
+func (e *ContinueException) Error() string {
+	return "continue"
+}
+
 type PrintCallback = func(string)
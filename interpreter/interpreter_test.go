@@ -1,9 +1,12 @@
 package interpreter
 
 import (
+	"errors"
+	"fmt"
 	"github.com/nesyuk/golox/scanner"
 	"github.com/nesyuk/golox/scanner/testutil"
 	"github.com/nesyuk/golox/token"
+	"strings"
 	"testing"
 )
 
@@ -397,6 +400,10 @@ func TestBlockStmt(t *testing.T) {
 		&token.PrintStmt{Expression: &token.VariableExpr{Name: tokB}},
 		&token.PrintStmt{Expression: &token.VariableExpr{Name: tokC}},
 	}}
+	// The block is not resolved, its variables are looked up as globals.
+	for _, name := range []string{"a", "b", "c"} {
+		i.globals.Define(name, nil)
+	}
 	got, err := i.exec(block)
 	if got != nil {
 		t.Fatalf("expect nil")
@@ -556,6 +563,120 @@ func (v *validator) validate(t *testing.T, prefix string, got, expect []string)
 	}
 }
 
+func TestRegisterFunc(t *testing.T) {
+	v := NewValidator()
+	i := New(v.onError, v.onPrint)
+	register := func(name string, fn interface{}) {
+		if err := i.RegisterFunc(name, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	register("half", func(n float64) float64 { return n / 2 })
+	register("repeat", func(s string, times int) string { return strings.Repeat(s, times) })
+	register("not", func(b bool) bool { return !b })
+	register("sum", func(first float64, rest ...float64) float64 {
+		for _, n := range rest {
+			first += n
+		}
+		return first
+	})
+	register("typeOf", func(value interface{}) string { return fmt.Sprintf("%T", value) })
+	register("fail", func() error { return errors.New("failed.") })
+	register("nothing", func() {})
+	register("global", func(i *Interpreter, name string) interface{} {
+		value, _ := i.Global(name)
+		return value
+	})
+
+	tests := []struct {
+		name         string
+		args         []interface{}
+		expect       interface{}
+		runtimeError string
+	}{
+		{"half", []interface{}{3.0}, 1.5, ""},
+		{"repeat", []interface{}{"ab", 2.0}, "abab", ""},
+		{"not", []interface{}{false}, true, ""},
+		{"sum", []interface{}{1.0}, 1.0, ""},
+		{"sum", []interface{}{1.0, 2.0, 3.0}, 6.0, ""},
+		{"typeOf", []interface{}{nil}, "<nil>", ""},
+		{"nothing", []interface{}{}, nil, ""},
+		{"global", []interface{}{"typeOf"}, i.globals.variables["typeOf"], ""},
+		{"half", []interface{}{"a"}, nil, "Argument 1 of 'half' must be a number."},
+		{"repeat", []interface{}{"a", 1.5}, nil, "Argument 2 of 'repeat' must be an integer."},
+		{"half", []interface{}{}, nil, "Expected 1 arguments but got 0."},
+		{"sum", []interface{}{}, nil, "Expected at least 1 arguments but got 0."},
+		{"fail", []interface{}{}, nil, "failed."},
+	}
+	for _, test := range tests {
+		paren := testutil.RightParen()
+		args := make([]token.Expr, 0)
+		for _, arg := range test.args {
+			args = append(args, &token.LiteralExpr{Value: arg})
+		}
+		got, err := i.eval(&token.CallExpr{
+			Callee:    &token.VariableExpr{Name: testutil.Identifier(test.name)},
+			Paren:     &paren,
+			Arguments: args,
+		})
+		if test.runtimeError != "" {
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) || runtimeErr.Message != test.runtimeError || runtimeErr.Token != &paren {
+				t.Errorf("expect runtime error '%v' at ')', got: %v", test.runtimeError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.expect {
+			t.Errorf("expect: %v, got: %v (%v)", test.expect, got, test.name)
+		}
+	}
+	if got := Stringify(i.globals.variables["half"]); got != "<native fn 'half'>" {
+		t.Errorf("expect: <native fn 'half'>, got: %v", got)
+	}
+}
+
+func TestRegisterFuncUnsupported(t *testing.T) {
+	i := New(nil, nil)
+	for _, fn := range []interface{}{
+		"not a function",
+		func(m map[string]int) {},
+		func(fn LoxCallable) {},
+		func() (int, int) { return 1, 2 },
+		func() (int, error, bool) { return 1, nil, false },
+	} {
+		if err := i.RegisterFunc("fn", fn); err == nil {
+			t.Errorf("expect error for %T", fn)
+		}
+	}
+}
+
+func TestNativeNames(t *testing.T) {
+	natives, names := Natives(), NativeNames()
+	if len(names) != len(natives) {
+		t.Fatalf("expect %v names, got: %v", len(natives), names)
+	}
+	for _, name := range names {
+		if _, exist := natives[name]; !exist {
+			t.Errorf("expect '%v' to be a native", name)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	i := New(nil, nil)
+	clock, ok := i.globals.variables["clock"].(LoxCallable)
+	if !ok {
+		t.Fatalf("expect clock to be callable")
+	}
+	got, err := i.Call(clock, []interface{}{})
+	if _, isNumber := got.(float64); err != nil || !isNumber {
+		t.Errorf("expect a number, got: %v (%v)", got, err)
+	}
+}
+
 func numberIdentifier(name string, value float64) *token.VarStmt {
 	tok := testutil.Identifier(name)
 	return &token.VarStmt{Name: tok, Initializer: &token.LiteralExpr{Value: testutil.Number(value)}}
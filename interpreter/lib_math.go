@@ -0,0 +1,39 @@
+package interpreter
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// mathLibrary returns the built-in math functions, random numbers come from a source owned by the library,
+// seed(n) makes the following random() calls reproducible.
+func mathLibrary() map[string]interface{} {
+	source := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return map[string]interface{}{
+		"floor": math.Floor,
+		"sqrt":  math.Sqrt,
+		"pow":   math.Pow,
+		"abs":   math.Abs,
+		"min":   minimum,
+		"max":   maximum,
+		// mod follows the sign of the dividend.
+		"mod":    math.Mod,
+		"random": source.Float64,
+		"seed":   source.Seed,
+	}
+}
+
+func minimum(first float64, rest ...float64) float64 {
+	for _, n := range rest {
+		first = math.Min(first, n)
+	}
+	return first
+}
+
+func maximum(first float64, rest ...float64) float64 {
+	for _, n := range rest {
+		first = math.Max(first, n)
+	}
+	return first
+}
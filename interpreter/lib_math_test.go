@@ -0,0 +1,74 @@
+package interpreter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMathLibrary(t *testing.T) {
+	natives := Natives()
+	tests := []struct {
+		name   string
+		args   []interface{}
+		expect interface{}
+		err    string
+	}{
+		{"floor", []interface{}{2.7}, 2.0, ""},
+		{"floor", []interface{}{-2.2}, -3.0, ""},
+		{"sqrt", []interface{}{16.0}, 4.0, ""},
+		{"pow", []interface{}{2.0, 10.0}, 1024.0, ""},
+		{"abs", []interface{}{-1.5}, 1.5, ""},
+		{"min", []interface{}{3.0}, 3.0, ""},
+		{"min", []interface{}{3.0, -1.0, 2.0}, -1.0, ""},
+		{"max", []interface{}{3.0, -1.0, 7.0}, 7.0, ""},
+		{"mod", []interface{}{7.0, 3.0}, 1.0, ""},
+		{"mod", []interface{}{-7.0, 3.0}, -1.0, ""},
+		{"mod", []interface{}{7.5, 2.0}, 1.5, ""},
+		{"sqrt", []interface{}{"16"}, nil, "Argument 1 of 'sqrt' must be a number."},
+		{"seed", []interface{}{1.5}, nil, "Argument 1 of 'seed' must be an integer."},
+	}
+	for _, test := range tests {
+		got, err := natives[test.name].Call(nil, test.args)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%v: expect error '%v', got: %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		} else if got != test.expect {
+			t.Errorf("%v: expect '%v', got: '%v'", test.name, test.expect, got)
+		}
+	}
+	if got, _ := natives["mod"].Call(nil, []interface{}{1.0, 0.0}); !math.IsNaN(got.(float64)) {
+		t.Errorf("mod: expect NaN, got: %v", got)
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	random := func(natives map[string]LoxCallable) []float64 {
+		if _, err := natives["seed"].Call(nil, []interface{}{42.0}); err != nil {
+			t.Fatal(err)
+		}
+		numbers := make([]float64, 0)
+		for idx := 0; idx < 5; idx++ {
+			n, err := natives["random"].Call(nil, []interface{}{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.(float64) < 0 || n.(float64) >= 1 {
+				t.Errorf("expect a number in [0, 1), got: %v", n)
+			}
+			numbers = append(numbers, n.(float64))
+		}
+		return numbers
+	}
+	first, second := random(Natives()), random(Natives())
+	for idx := range first {
+		if first[idx] != second[idx] {
+			t.Errorf("expect the same sequence after seed, got: %v and %v", first, second)
+			break
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringLibrary holds the built-in string functions, indices count characters, not bytes.
+var stringLibrary = map[string]interface{}{
+	"len":        strLen,
+	"substring":  substring,
+	"indexOf":    indexOf,
+	"split":      split,
+	"join":       join,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"trim":       strings.TrimSpace,
+	"replace":    replace,
+	"startsWith": strings.HasPrefix,
+	"format":     format,
+	"str":        Stringify,
+}
+
+func strLen(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case string:
+		return utf8.RuneCountInString(v), nil
+	case *loxList:
+		return len(v.elements), nil
+	case *loxMap:
+		return len(v.keys), nil
+	}
+	return 0, errors.New("Argument 1 of 'len' must be a string, a list or a map.")
+}
+
+func substring(s string, start int, end int) (string, error) {
+	runes := []rune(s)
+	if start < 0 || end > len(runes) || start > end {
+		return "", fmt.Errorf("Substring range [%d, %d) is out of bounds for length %d.", start, end, len(runes))
+	}
+	return string(runes[start:end]), nil
+}
+
+func indexOf(s string, substr string) int {
+	idx := strings.Index(s, substr)
+	if idx < 0 {
+		return idx
+	}
+	return utf8.RuneCountInString(s[:idx])
+}
+
+func split(s string, separator string) *loxList {
+	parts := strings.Split(s, separator)
+	elements := make([]interface{}, len(parts))
+	for idx, part := range parts {
+		elements[idx] = part
+	}
+	return &loxList{elements}
+}
+
+func join(list interface{}, separator string) (string, error) {
+	l, ok := list.(*loxList)
+	if !ok {
+		return "", errors.New("Argument 1 of 'join' must be a list.")
+	}
+	parts := make([]string, len(l.elements))
+	for idx, element := range l.elements {
+		parts[idx] = Stringify(element)
+	}
+	return strings.Join(parts, separator), nil
+}
+
+func replace(s string, old string, new string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+// format replaces every {} placeholder with the next argument.
+func format(template string, args ...interface{}) (string, error) {
+	placeholders := strings.Count(template, "{}")
+	if placeholders != len(args) {
+		return "", fmt.Errorf("Expected %d format arguments but got %d.", placeholders, len(args))
+	}
+	var sb strings.Builder
+	for _, arg := range args {
+		idx := strings.Index(template, "{}")
+		sb.WriteString(template[:idx])
+		sb.WriteString(Stringify(arg))
+		template = template[idx+2:]
+	}
+	sb.WriteString(template)
+	return sb.String(), nil
+}
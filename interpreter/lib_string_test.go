@@ -0,0 +1,55 @@
+package interpreter
+
+import (
+	"testing"
+)
+
+func TestStringLibrary(t *testing.T) {
+	natives := Natives()
+	tests := []struct {
+		name   string
+		args   []interface{}
+		expect interface{}
+		err    string
+	}{
+		{"len", []interface{}{"héllo"}, 5.0, ""},
+		{"len", []interface{}{split("a,b", ",")}, 2.0, ""},
+		{"len", []interface{}{1.0}, nil, "Argument 1 of 'len' must be a string, a list or a map."},
+		{"substring", []interface{}{"héllo", 1.0, 3.0}, "él", ""},
+		{"substring", []interface{}{"hello", 0.0, 0.0}, "", ""},
+		{"substring", []interface{}{"hello", 2.0, 6.0}, nil, "Substring range [2, 6) is out of bounds for length 5."},
+		{"substring", []interface{}{"hello", 0.5, 1.0}, nil, "Argument 2 of 'substring' must be an integer."},
+		{"indexOf", []interface{}{"héllo", "l"}, 2.0, ""},
+		{"indexOf", []interface{}{"hello", "x"}, -1.0, ""},
+		{"join", []interface{}{split("a b c", " "), "-"}, "a-b-c", ""},
+		{"join", []interface{}{split("abc", ""), ","}, "a,b,c", ""},
+		{"join", []interface{}{"abc", ","}, nil, "Argument 1 of 'join' must be a list."},
+		{"upper", []interface{}{"Lox"}, "LOX", ""},
+		{"lower", []interface{}{"Lox"}, "lox", ""},
+		{"trim", []interface{}{"  lox\n"}, "lox", ""},
+		{"replace", []interface{}{"a.b.c", ".", "/"}, "a/b/c", ""},
+		{"startsWith", []interface{}{"golox", "go"}, true, ""},
+		{"startsWith", []interface{}{"golox", "lox"}, false, ""},
+		{"startsWith", []interface{}{nil, "lox"}, nil, "Argument 1 of 'startsWith' must be a string."},
+		{"format", []interface{}{"{} + {} = {}", 1.5, "b", nil}, "1.5 + b = nil", ""},
+		{"format", []interface{}{"no placeholders"}, "no placeholders", ""},
+		{"format", []interface{}{"{} {}", 1.5}, nil, "Expected 2 format arguments but got 1."},
+	}
+	for _, test := range tests {
+		got, err := natives[test.name].Call(nil, test.args)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%v: expect error '%v', got: %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		} else if got != test.expect {
+			t.Errorf("%v: expect '%v', got: '%v'", test.name, test.expect, got)
+		}
+	}
+	if got := split("a,b", ",").String(); got != "[a, b]" {
+		t.Errorf("expect '[a, b]', got: '%v'", got)
+	}
+}
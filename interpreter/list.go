@@ -0,0 +1,109 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nesyuk/golox/scanner"
+	"math"
+	"strings"
+)
+
+type loxList struct {
+	elements []interface{}
+}
+
+// NewList creates a list value, the bytecode VM shares lists with the interpreter.
+func NewList(elements []interface{}) interface{} {
+	return &loxList{elements}
+}
+
+// GetIndex evaluates object[index] for lists and maps.
+func GetIndex(object interface{}, bracket *scanner.Token, index interface{}) (interface{}, error) {
+	switch o := object.(type) {
+	case *loxList:
+		idx, err := o.index(bracket, index)
+		if err != nil {
+			return nil, err
+		}
+		return o.elements[idx], nil
+	case *loxMap:
+		return o.get(bracket, index)
+	}
+	return nil, &RuntimeError{bracket, "Only lists and maps can be indexed."}
+}
+
+// SetIndex evaluates object[index] = value for lists and maps.
+func SetIndex(object interface{}, bracket *scanner.Token, index interface{}, value interface{}) error {
+	switch o := object.(type) {
+	case *loxList:
+		idx, err := o.index(bracket, index)
+		if err != nil {
+			return err
+		}
+		o.elements[idx] = value
+		return nil
+	case *loxMap:
+		if err := checkKey(index); err != nil {
+			return &RuntimeError{bracket, err.Error()}
+		}
+		o.set(index, value)
+		return nil
+	}
+	return &RuntimeError{bracket, "Only lists and maps can be indexed."}
+}
+
+// Get returns a list method bound to the list.
+func (l *loxList) Get(name *scanner.Token) (interface{}, error) {
+	var method interface{}
+	switch *name.Lexeme {
+	case "push":
+		method = l.push
+	case "pop":
+		method = l.pop
+	case "len":
+		method = l.len
+	default:
+		return nil, &RuntimeError{
+			Token:   name,
+			Message: fmt.Sprintf("Undefined property '%v'.", *name.Lexeme),
+		}
+	}
+	return newNativeFunction(*name.Lexeme, method)
+}
+
+func (l *loxList) push(value interface{}) {
+	l.elements = append(l.elements, value)
+}
+
+func (l *loxList) pop() (interface{}, error) {
+	if len(l.elements) == 0 {
+		return nil, errors.New("Can't pop from an empty list.")
+	}
+	last := l.elements[len(l.elements)-1]
+	l.elements = l.elements[:len(l.elements)-1]
+	return last, nil
+}
+
+func (l *loxList) len() int {
+	return len(l.elements)
+}
+
+// index validates a Lox value used as a subscript of the list.
+func (l *loxList) index(bracket *scanner.Token, value interface{}) (int, error) {
+	n, ok := value.(float64)
+	if !ok || n != math.Trunc(n) {
+		return 0, &RuntimeError{bracket, "List index must be an integer."}
+	}
+	if n < 0 || n >= float64(len(l.elements)) {
+		return 0, &RuntimeError{bracket, fmt.Sprintf("List index %v is out of bounds for length %d.", n, len(l.elements))}
+	}
+	return int(n), nil
+}
+
+func (l *loxList) String() string {
+	parts := make([]string, len(l.elements))
+	for idx, element := range l.elements {
+		parts[idx] = Stringify(element)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,145 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nesyuk/golox/scanner"
+	"math"
+	"strings"
+)
+
+// loxMap keys are strings, numbers other than NaN or booleans, Go equality of these matches isEqual.
+type loxMap struct {
+	entries map[interface{}]interface{}
+	// Keys in insertion order, printing and iteration follow it.
+	keys []interface{}
+}
+
+// NewMap creates a map value from its literal, keys are checked at the brace of the literal.
+func NewMap(brace *scanner.Token, keys []interface{}, values []interface{}) (interface{}, error) {
+	m := &loxMap{entries: make(map[interface{}]interface{}, len(keys))}
+	for idx, key := range keys {
+		if err := checkKey(key); err != nil {
+			return nil, &RuntimeError{brace, err.Error()}
+		}
+		m.set(key, values[idx])
+	}
+	return m, nil
+}
+
+func checkKey(key interface{}) error {
+	switch k := key.(type) {
+	case float64:
+		if math.IsNaN(k) {
+			// NaN is not equal to itself, its entries could never be found again.
+			return errors.New("Map key can't be NaN.")
+		}
+		return nil
+	case string, bool:
+		return nil
+	}
+	return errors.New("Map key must be a string, number or boolean.")
+}
+
+func (m *loxMap) get(bracket *scanner.Token, key interface{}) (interface{}, error) {
+	if err := checkKey(key); err != nil {
+		return nil, &RuntimeError{bracket, err.Error()}
+	}
+	value, exist := m.entries[key]
+	if !exist {
+		return nil, &RuntimeError{bracket, fmt.Sprintf("Undefined key '%v'.", Stringify(key))}
+	}
+	return value, nil
+}
+
+func (m *loxMap) set(key interface{}, value interface{}) {
+	if _, exist := m.entries[key]; !exist {
+		m.keys = append(m.keys, key)
+	}
+	m.entries[key] = value
+}
+
+// Get returns a map method bound to the map.
+func (m *loxMap) Get(name *scanner.Token) (interface{}, error) {
+	var method interface{}
+	switch *name.Lexeme {
+	case "keys":
+		method = m.keyList
+	case "values":
+		method = m.values
+	case "entries":
+		method = m.entryList
+	case "has":
+		method = m.has
+	case "remove":
+		method = m.remove
+	case "len":
+		method = m.len
+	default:
+		return nil, &RuntimeError{
+			Token:   name,
+			Message: fmt.Sprintf("Undefined property '%v'.", *name.Lexeme),
+		}
+	}
+	return newNativeFunction(*name.Lexeme, method)
+}
+
+func (m *loxMap) keyList() *loxList {
+	return &loxList{append([]interface{}{}, m.keys...)}
+}
+
+func (m *loxMap) values() *loxList {
+	values := make([]interface{}, len(m.keys))
+	for idx, key := range m.keys {
+		values[idx] = m.entries[key]
+	}
+	return &loxList{values}
+}
+
+// entryList returns [key, value] pairs.
+func (m *loxMap) entryList() *loxList {
+	entries := make([]interface{}, len(m.keys))
+	for idx, key := range m.keys {
+		entries[idx] = &loxList{[]interface{}{key, m.entries[key]}}
+	}
+	return &loxList{entries}
+}
+
+func (m *loxMap) has(key interface{}) (bool, error) {
+	if err := checkKey(key); err != nil {
+		return false, err
+	}
+	_, exist := m.entries[key]
+	return exist, nil
+}
+
+// remove deletes the key and returns its value, or nil when the key is missing.
+func (m *loxMap) remove(key interface{}) (interface{}, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	value, exist := m.entries[key]
+	if !exist {
+		return nil, nil
+	}
+	delete(m.entries, key)
+	for idx := range m.keys {
+		if m.keys[idx] == key {
+			m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+			break
+		}
+	}
+	return value, nil
+}
+
+func (m *loxMap) len() int {
+	return len(m.keys)
+}
+
+func (m *loxMap) String() string {
+	parts := make([]string, len(m.keys))
+	for idx, key := range m.keys {
+		parts[idx] = Stringify(key) + ": " + Stringify(m.entries[key])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
@@ -0,0 +1,131 @@
+package interpreter
+
+import (
+	"fmt"
+	"github.com/nesyuk/golox/scanner"
+	"github.com/nesyuk/golox/token"
+	"path/filepath"
+	"strings"
+)
+
+// ModuleLoader reads, parses and resolves an imported file, the path is already canonical.
+type ModuleLoader func(path string) ([]token.Stmt, error)
+
+// loxModule is the namespace of an imported file, its properties are the globals the file defined.
+type loxModule struct {
+	name    string
+	globals map[string]interface{}
+	// Built-in functions are globals of every module but not a part of it.
+	natives map[string]LoxCallable
+}
+
+// NewModule returns the namespace of a module, the globals stay shared with the module's functions.
+func NewModule(path string, globals map[string]interface{}, natives map[string]LoxCallable) Object {
+	name := filepath.Base(path)
+	return &loxModule{strings.TrimSuffix(name, filepath.Ext(name)), globals, natives}
+}
+
+func (m *loxModule) Get(name *scanner.Token) (interface{}, error) {
+	value, exist := m.globals[*name.Lexeme]
+	if native, isNative := m.natives[*name.Lexeme]; exist && (!isNative || value != native) {
+		return value, nil
+	}
+	return nil, &RuntimeError{
+		Token:   name,
+		Message: fmt.Sprintf("Module '%v' has no member '%v'.", m.name, *name.Lexeme),
+	}
+}
+
+func (m *loxModule) String() string {
+	return fmt.Sprintf("<module '%v'.>", m.name)
+}
+
+// ModulePath returns the canonical path of a module imported by the given file,
+// relative paths are relative to the directory of the importing file.
+func ModulePath(importer string, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(importer), path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// ImportCycle describes the chain of imports leading back to the module being imported.
+func ImportCycle(importing []string, path string) string {
+	chain := make([]string, 0, len(importing)+1)
+	for idx, p := range importing {
+		if p == path {
+			for _, module := range importing[idx:] {
+				chain = append(chain, filepath.Base(module))
+			}
+			break
+		}
+	}
+	chain = append(chain, filepath.Base(path))
+	return fmt.Sprintf("Import cycle: %v.", strings.Join(chain, " -> "))
+}
+
+// SetModuleLoader enables import statements, filename is the path of the program being run.
+// Imports of a program without a file are relative to the working directory.
+func (i *Interpreter) SetModuleLoader(filename string, loader ModuleLoader) {
+	if filename != "" {
+		filename = ModulePath("", filename)
+	}
+	i.loader = loader
+	i.importing = []string{filename}
+}
+
+func (i *Interpreter) VisitImportStmt(stmt *token.ImportStmt) (interface{}, error) {
+	module, err := i.importModule(stmt.Path)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.Name != nil {
+		i.env.Define(*stmt.Name.Lexeme, module)
+		return nil, nil
+	}
+	for _, name := range stmt.Names {
+		value, err := module.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		i.env.Define(*name.Lexeme, value)
+	}
+	return nil, nil
+}
+
+// importModule executes a module the first time it is imported, with its own globals.
+func (i *Interpreter) importModule(pathToken *scanner.Token) (Object, error) {
+	if i.loader == nil {
+		return nil, &RuntimeError{pathToken, "Can't import modules here."}
+	}
+	path := ModulePath(i.importing[len(i.importing)-1], pathToken.Literal.(string))
+	if module, exist := i.modules[path]; exist {
+		return module, nil
+	}
+	for _, p := range i.importing {
+		if p == path {
+			return nil, &RuntimeError{pathToken, ImportCycle(i.importing, path)}
+		}
+	}
+	statements, err := i.loader(path)
+	if err != nil {
+		return nil, &RuntimeError{pathToken, fmt.Sprintf("Can't import '%v': %v.", pathToken.Literal, err)}
+	}
+
+	globals := NewEnvironment()
+	for name, native := range i.natives {
+		globals.Define(name, native)
+	}
+	i.importing = append(i.importing, path)
+	_, err = i.execBlock(statements, globals)
+	i.importing = i.importing[:len(i.importing)-1]
+	if err != nil {
+		return nil, err
+	}
+	module := NewModule(path, globals.variables, i.natives)
+	i.modules[path] = module
+	return module, nil
+}
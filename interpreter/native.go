@@ -0,0 +1,239 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var (
+	interpreterType = reflect.TypeOf((*Interpreter)(nil))
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// VariadicCallable is implemented by callables accepting Arity() or more arguments.
+type VariadicCallable interface {
+	LoxCallable
+	Variadic() bool
+}
+
+// nativeFunction adapts a Go function to LoxCallable, arguments are converted to the Go parameter types.
+type nativeFunction struct {
+	name string
+	fn   reflect.Value
+	// Parameter types without the injected *Interpreter, the last one is the element type for variadic functions.
+	params          []reflect.Type
+	withInterpreter bool
+	variadic        bool
+}
+
+// RegisterFunc binds a Go function as a global Lox function.
+//
+// Parameters may be float64 (or any other number type, integers must be whole numbers), string, bool
+// or interface{} for any value, and the function may be variadic. Lox functions are passed as interface{}
+// values only: the backends represent them differently, so natives can't call them.
+// If the first parameter is *Interpreter, the running interpreter is passed in.
+// The function returns nothing, a value, an error or a value and an error; errors become runtime errors.
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	native, err := newNativeFunction(name, fn)
+	if err != nil {
+		return err
+	}
+	i.globals.Define(name, native)
+	return nil
+}
+
+// NewNative adapts a Go function to LoxCallable without defining it, see RegisterFunc.
+func NewNative(name string, fn interface{}) (LoxCallable, error) {
+	return newNativeFunction(name, fn)
+}
+
+// nativeNames are the names of the built-in library, kept in sync with Natives.
+var nativeNames = []string{
+	"clock",
+	"len", "substring", "indexOf", "split", "join", "upper", "lower", "trim", "replace", "startsWith", "format", "str",
+	"floor", "sqrt", "pow", "abs", "min", "max", "mod", "random", "seed",
+}
+
+// NativeNames returns the names of the built-in library without creating the functions.
+func NativeNames() []string {
+	return append([]string(nil), nativeNames...)
+}
+
+// Natives returns the built-in library, every global environment starts with these functions.
+func Natives() map[string]LoxCallable {
+	natives := map[string]LoxCallable{"clock": &clock{}}
+	for _, library := range []map[string]interface{}{stringLibrary, mathLibrary()} {
+		for name, fn := range library {
+			native, err := newNativeFunction(name, fn)
+			if err != nil {
+				panic(err)
+			}
+			natives[name] = native
+		}
+	}
+	return natives
+}
+
+func newNativeFunction(name string, fn interface{}) (*nativeFunction, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return nil, fmt.Errorf("native '%v' must be a function, got %T", name, fn)
+	}
+	t := v.Type()
+	native := &nativeFunction{name: name, fn: v, variadic: t.IsVariadic()}
+	for idx := 0; idx < t.NumIn(); idx++ {
+		param := t.In(idx)
+		if idx == 0 && param == interpreterType {
+			native.withInterpreter = true
+			continue
+		}
+		if idx == t.NumIn()-1 && native.variadic {
+			param = param.Elem()
+		}
+		if !isSupportedParam(param) {
+			return nil, fmt.Errorf("native '%v' has unsupported parameter type %v", name, param)
+		}
+		native.params = append(native.params, param)
+	}
+	switch {
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("native '%v' must return at most a value and an error", name)
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("native '%v' must return an error as the second value", name)
+	}
+	return native, nil
+}
+
+func isSupportedParam(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64, reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	}
+	return false
+}
+
+func (fn *nativeFunction) Arity() int {
+	if fn.variadic {
+		return len(fn.params) - 1
+	}
+	return len(fn.params)
+}
+
+func (fn *nativeFunction) Variadic() bool {
+	return fn.variadic
+}
+
+func (fn *nativeFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	in := make([]reflect.Value, 0, len(arguments)+1)
+	if fn.withInterpreter {
+		in = append(in, reflect.ValueOf(interpreter))
+	}
+	for idx, arg := range arguments {
+		param := fn.params[len(fn.params)-1]
+		if idx < len(fn.params) {
+			param = fn.params[idx]
+		}
+		value, err := fromLox(arg, param)
+		if err != nil {
+			return nil, fmt.Errorf("Argument %d of '%v' %v.", idx+1, fn.name, err.Error())
+		}
+		in = append(in, value)
+	}
+
+	out := fn.fn.Call(in)
+	if len(out) > 0 {
+		if err, ok := out[len(out)-1].Interface().(error); ok && out[len(out)-1].Type() == errorType {
+			return nil, err
+		}
+		if out[0].Type() != errorType {
+			return ToLox(out[0].Interface()), nil
+		}
+	}
+	return nil, nil
+}
+
+func (fn *nativeFunction) String() string {
+	return fmt.Sprintf("<native fn '%v'>", fn.name)
+}
+
+// fromLox converts a Lox value to the Go type of a native function parameter.
+func fromLox(value interface{}, t reflect.Type) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.Interface:
+		if value == nil {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(value), nil
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			return reflect.ValueOf(s).Convert(t), nil
+		}
+		return reflect.Value{}, errors.New("must be a string")
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			return reflect.ValueOf(b).Convert(t), nil
+		}
+		return reflect.Value{}, errors.New("must be a boolean")
+	case reflect.Float32, reflect.Float64:
+		if n, ok := value.(float64); ok {
+			return reflect.ValueOf(n).Convert(t), nil
+		}
+		return reflect.Value{}, errors.New("must be a number")
+	}
+	// Integer kinds
+	n, ok := value.(float64)
+	if !ok || n != math.Trunc(n) {
+		return reflect.Value{}, errors.New("must be an integer")
+	}
+	v := reflect.New(t).Elem()
+	if t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 {
+		if n < 0 || v.OverflowUint(uint64(n)) {
+			return reflect.Value{}, errors.New("is out of range")
+		}
+		v.SetUint(uint64(n))
+	} else {
+		if v.OverflowInt(int64(n)) {
+			return reflect.Value{}, errors.New("is out of range")
+		}
+		v.SetInt(int64(n))
+	}
+	return v, nil
+}
+
+// ToLox converts a Go value to a Lox value, all numbers become float64.
+func ToLox(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	}
+	return value
+}
+
+// ArityError describes a mismatch between the callable parameters and the number of arguments.
+func ArityError(callable LoxCallable, count int) string {
+	if v, ok := callable.(VariadicCallable); ok && v.Variadic() {
+		if count < callable.Arity() {
+			return fmt.Sprintf("Expected at least %d arguments but got %d.", callable.Arity(), count)
+		}
+		return ""
+	}
+	if count != callable.Arity() {
+		return fmt.Sprintf("Expected %d arguments but got %d.", callable.Arity(), count)
+	}
+	return ""
+}
@@ -0,0 +1,389 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/nesyuk/golox/diagnostic"
+	"github.com/nesyuk/golox/resolver"
+	"github.com/nesyuk/golox/scanner"
+	"github.com/nesyuk/golox/token"
+	"sort"
+	"strings"
+)
+
+type variableKind uint8
+
+const (
+	LOCAL variableKind = iota
+	PARAMETER
+	// A variable that is not reported when it is never read, e.g. the exception of a catch clause.
+	IGNORED
+)
+
+type variable struct {
+	name *scanner.Token
+	kind variableKind
+	// Local functions and classes are reported with their own description.
+	description string
+	used        bool
+}
+
+type linter struct {
+	scopes []map[string]*variable
+	// Globals declared by the program, locals with the same name shadow them.
+	globals map[string]bool
+	// Built-in functions, they are globals too but they are not shadowed: a parameter named max is fine.
+	natives  map[string]bool
+	warnings []diagnostic.Diagnostic
+}
+
+// Lint looks for suspicious code in statements that passed the resolver: unused local variables,
+// code after return, shadowed variables, self-comparisons and undefined globals, natives are the names
+// of the built-in functions. Names starting with an underscore are never reported as unused.
+func Lint(statements []token.Stmt, natives []string) []diagnostic.Diagnostic {
+	l := &linter{globals: make(map[string]bool), natives: make(map[string]bool)}
+	for _, name := range resolver.TopLevelNames(statements) {
+		l.globals[name] = true
+	}
+	for _, name := range natives {
+		l.natives[name] = true
+	}
+	l.statements(statements)
+	sort.SliceStable(l.warnings, func(i, j int) bool {
+		return l.warnings[i].Span.Offset < l.warnings[j].Span.Offset
+	})
+	return l.warnings
+}
+
+func (l *linter) warn(severity diagnostic.Severity, tok scanner.Token, message string) {
+	l.warnings = append(l.warnings, diagnostic.Diagnostic{Severity: severity, Span: diagnostic.TokenSpan(tok), Message: message})
+}
+
+func (l *linter) beginScope() {
+	l.scopes = append(l.scopes, make(map[string]*variable))
+}
+
+func (l *linter) endScope() {
+	for name, v := range l.scopes[len(l.scopes)-1] {
+		if v.used || strings.HasPrefix(name, "_") {
+			continue
+		}
+		switch v.kind {
+		case LOCAL:
+			l.warn(diagnostic.WARNING, *v.name, fmt.Sprintf("Local %v '%v' is never used.", v.description, name))
+		case PARAMETER:
+			l.warn(diagnostic.WARNING, *v.name, fmt.Sprintf("Parameter '%v' is never used.", name))
+		}
+	}
+	l.scopes = l.scopes[:len(l.scopes)-1]
+}
+
+// declare adds a local variable to the innermost scope, globals are collected up front.
+func (l *linter) declare(name *scanner.Token, kind variableKind, description string) {
+	if len(l.scopes) == 0 {
+		return
+	}
+	if l.lookup(*name.Lexeme) != nil || l.globals[*name.Lexeme] {
+		l.warn(diagnostic.WARNING, *name, fmt.Sprintf("Declaration of '%v' shadows a variable in an outer scope.", *name.Lexeme))
+	}
+	l.scopes[len(l.scopes)-1][*name.Lexeme] = &variable{name: name, kind: kind, description: description}
+}
+
+func (l *linter) lookup(name string) *variable {
+	for i := len(l.scopes) - 1; i >= 0; i-- {
+		if v, exist := l.scopes[i][name]; exist {
+			return v
+		}
+	}
+	return nil
+}
+
+func (l *linter) use(name scanner.Token) {
+	if v := l.lookup(*name.Lexeme); v != nil {
+		v.used = true
+	} else if !l.globals[*name.Lexeme] && !l.natives[*name.Lexeme] {
+		l.warn(diagnostic.WARNING, name, fmt.Sprintf("Undefined variable '%v'.", *name.Lexeme))
+	}
+}
+
+// statements lints a list of statements and reports the first statement that can't be reached.
+func (l *linter) statements(stmts []token.Stmt) {
+	reported := false
+	for idx, stmt := range stmts {
+		l.stmt(stmt)
+		if reported || idx == len(stmts)-1 {
+			continue
+		}
+		if keyword := terminator(stmt); keyword != nil {
+			l.warn(diagnostic.WARNING, *keyword, fmt.Sprintf("Code after '%v' is unreachable.", *keyword.Lexeme))
+			reported = true
+		}
+	}
+}
+
+// terminator returns the keyword of a statement that never continues with the next one.
+func terminator(stmt token.Stmt) *scanner.Token {
+	switch s := stmt.(type) {
+	case *token.ReturnStmt:
+		return s.Keyword
+	case *token.ThrowStmt:
+		return s.Keyword
+	case *token.BreakStmt:
+		return s.Keyword
+	case *token.ContinueStmt:
+		return s.Keyword
+	}
+	return nil
+}
+
+func (l *linter) block(stmts []token.Stmt) {
+	l.beginScope()
+	l.statements(stmts)
+	l.endScope()
+}
+
+func (l *linter) stmt(stmt token.Stmt) {
+	_, _ = stmt.Accept(l)
+}
+
+func (l *linter) expr(expr token.Expr) {
+	if expr != nil {
+		_, _ = expr.Accept(l)
+	}
+}
+
+func (l *linter) function(fn *token.FunctionStmt) {
+	l.beginScope()
+	for _, param := range fn.Params {
+		l.declare(param, PARAMETER, "parameter")
+	}
+	l.statements(fn.Body)
+	l.endScope()
+}
+
+// path describes a variable or a chain of properties, other expressions have no path.
+func path(expr token.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *token.VariableExpr:
+		return *e.Name.Lexeme, true
+	case *token.ThisExpr:
+		return "this", true
+	case *token.GetExpr:
+		object, ok := path(e.Object)
+		return object + "." + *e.Name.Lexeme, ok
+	case *token.GroupingExpr:
+		return path(e.Expression)
+	}
+	return "", false
+}
+
+func (l *linter) VisitAssignExpr(expr *token.AssignExpr) (interface{}, error) {
+	l.expr(expr.Value)
+	if l.lookup(*expr.Name.Lexeme) == nil && !l.globals[*expr.Name.Lexeme] && !l.natives[*expr.Name.Lexeme] {
+		l.warn(diagnostic.WARNING, expr.Name, fmt.Sprintf("Assignment to undefined variable '%v'.", *expr.Name.Lexeme))
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitLiteralExpr(expr *token.LiteralExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *linter) VisitLogicalExpr(expr *token.LogicalExpr) (interface{}, error) {
+	l.expr(expr.Left)
+	l.expr(expr.Right)
+	return nil, nil
+}
+
+func (l *linter) VisitSetExpr(expr *token.SetExpr) (interface{}, error) {
+	l.expr(expr.Value)
+	l.expr(expr.Object)
+	return nil, nil
+}
+
+func (l *linter) VisitSuperExpr(expr *token.SuperExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *linter) VisitThisExpr(expr *token.ThisExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *linter) VisitUnaryExpr(expr *token.UnaryExpr) (interface{}, error) {
+	l.expr(expr.Right)
+	return nil, nil
+}
+
+func (l *linter) VisitCallExpr(expr *token.CallExpr) (interface{}, error) {
+	l.expr(expr.Callee)
+	for _, arg := range expr.Arguments {
+		l.expr(arg)
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitGetExpr(expr *token.GetExpr) (interface{}, error) {
+	l.expr(expr.Object)
+	return nil, nil
+}
+
+func (l *linter) VisitVariableExpr(expr *token.VariableExpr) (interface{}, error) {
+	l.use(expr.Name)
+	return nil, nil
+}
+
+func (l *linter) VisitBinaryExpr(expr *token.BinaryExpr) (interface{}, error) {
+	l.expr(expr.Left)
+	l.expr(expr.Right)
+	switch expr.Operator.TokenType {
+	case scanner.EQUAL_EQUAL, scanner.BANG_EQUAL, scanner.LESS, scanner.LESS_EQUAL, scanner.GREATER, scanner.GREATER_EQUAL:
+		left, ok := path(expr.Left)
+		if right, isPath := path(expr.Right); ok && isPath && left == right {
+			l.warn(diagnostic.WARNING, expr.Operator, fmt.Sprintf("Comparison of '%v' with itself.", left))
+		}
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitGroupingExpr(expr *token.GroupingExpr) (interface{}, error) {
+	l.expr(expr.Expression)
+	return nil, nil
+}
+
+func (l *linter) VisitLambdaExpr(expr *token.LambdaExpr) (interface{}, error) {
+	l.function(expr.Function)
+	return nil, nil
+}
+
+func (l *linter) VisitListExpr(expr *token.ListExpr) (interface{}, error) {
+	for _, element := range expr.Elements {
+		l.expr(element)
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitMapExpr(expr *token.MapExpr) (interface{}, error) {
+	for idx := range expr.Keys {
+		l.expr(expr.Keys[idx])
+		l.expr(expr.Values[idx])
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitIndexExpr(expr *token.IndexExpr) (interface{}, error) {
+	l.expr(expr.Object)
+	l.expr(expr.Index)
+	return nil, nil
+}
+
+func (l *linter) VisitIndexSetExpr(expr *token.IndexSetExpr) (interface{}, error) {
+	l.expr(expr.Value)
+	l.expr(expr.Object)
+	l.expr(expr.Index)
+	return nil, nil
+}
+
+func (l *linter) VisitInterpolationExpr(expr *token.InterpolationExpr) (interface{}, error) {
+	for _, part := range expr.Parts {
+		l.expr(part)
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitBlockStmt(stmt *token.BlockStmt) (interface{}, error) {
+	l.block(stmt.Statements)
+	return nil, nil
+}
+
+func (l *linter) VisitBreakStmt(stmt *token.BreakStmt) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *linter) VisitContinueStmt(stmt *token.ContinueStmt) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *linter) VisitClassStmt(stmt *token.ClassStmt) (interface{}, error) {
+	l.declare(stmt.Name, LOCAL, "class")
+	if stmt.Superclass != nil {
+		l.use(stmt.Superclass.Name)
+	}
+	for _, method := range stmt.Methods {
+		l.function(method)
+	}
+	for _, method := range stmt.ClassMethods {
+		l.function(method)
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitExpressionStmt(stmt *token.ExpressionStmt) (interface{}, error) {
+	l.expr(stmt.Expression)
+	return nil, nil
+}
+
+func (l *linter) VisitFunctionStmt(stmt *token.FunctionStmt) (interface{}, error) {
+	l.declare(stmt.Name, LOCAL, "function")
+	l.function(stmt)
+	return nil, nil
+}
+
+func (l *linter) VisitIfStmt(stmt *token.IfStmt) (interface{}, error) {
+	l.expr(stmt.Condition)
+	l.stmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		l.stmt(stmt.ElseBranch)
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitImportStmt(stmt *token.ImportStmt) (interface{}, error) {
+	if stmt.Name != nil {
+		l.declare(stmt.Name, LOCAL, "module")
+	}
+	for _, name := range stmt.Names {
+		l.declare(name, LOCAL, "variable")
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitPrintStmt(stmt *token.PrintStmt) (interface{}, error) {
+	l.expr(stmt.Expression)
+	return nil, nil
+}
+
+func (l *linter) VisitReturnStmt(stmt *token.ReturnStmt) (interface{}, error) {
+	l.expr(stmt.Value)
+	return nil, nil
+}
+
+func (l *linter) VisitThrowStmt(stmt *token.ThrowStmt) (interface{}, error) {
+	l.expr(stmt.Value)
+	return nil, nil
+}
+
+func (l *linter) VisitTryStmt(stmt *token.TryStmt) (interface{}, error) {
+	l.block(stmt.Body)
+	if stmt.CatchName != nil {
+		l.beginScope()
+		l.declare(stmt.CatchName, IGNORED, "variable")
+		l.statements(stmt.CatchBody)
+		l.endScope()
+	}
+	if stmt.FinallyBody != nil {
+		l.block(stmt.FinallyBody)
+	}
+	return nil, nil
+}
+
+func (l *linter) VisitWhileStmt(stmt *token.WhileStmt) (interface{}, error) {
+	l.expr(stmt.Condition)
+	l.stmt(stmt.Body)
+	l.expr(stmt.Increment)
+	return nil, nil
+}
+
+func (l *linter) VisitVarStmt(stmt *token.VarStmt) (interface{}, error) {
+	l.expr(stmt.Initializer)
+	l.declare(&stmt.Name, LOCAL, "variable")
+	return nil, nil
+}
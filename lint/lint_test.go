@@ -0,0 +1,78 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/nesyuk/golox/diagnostic"
+	"github.com/nesyuk/golox/parser"
+	"github.com/nesyuk/golox/scanner"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		source string
+		expect []string
+	}{
+		{"var a = 1;\nfun f(x) { return x + a; }\nprint f(1);", []string{}},
+		{"fun f() { var a = 1; var b = 2; print b; }", []string{"warning 1:15 Local variable 'a' is never used."}},
+		{"fun f(a, b, _c) { return a; }", []string{"warning 1:10 Parameter 'b' is never used."}},
+		{"fun f() { fun g() {} class C {} var _ignored; }", []string{
+			"warning 1:15 Local function 'g' is never used.",
+			"warning 1:28 Local class 'C' is never used.",
+		}},
+		{"fun f() { var a = 1; a = 2; }", []string{"warning 1:15 Local variable 'a' is never used."}},
+		{"fun f() { var a = 1; return fun () { return a; }; }", []string{}},
+		{"fun f() { return 1; print 2; print 3; }", []string{"warning 1:11 Code after 'return' is unreachable."}},
+		{"while (true) { break; print 1; }\nwhile (true) { continue; }", []string{"warning 1:16 Code after 'break' is unreachable."}},
+		{"fun f() { throw \"x\"; print 1; }", []string{"warning 1:11 Code after 'throw' is unreachable."}},
+		{"var a = 1;\n{ var a = 2; print a; }", []string{"warning 2:7 Declaration of 'a' shadows a variable in an outer scope."}},
+		{"fun f(a) { { var a = 2; print a; } return a; }", []string{"warning 1:18 Declaration of 'a' shadows a variable in an outer scope."}},
+		{"fun f(clock, max) { return clock + max; }", []string{}},
+		{"for (var i = 0; i < 1; i = i + 1) { for (var i = 0; i < 1; i = i + 1) print i; }", []string{"warning 1:46 Declaration of 'i' shadows a variable in an outer scope."}},
+		{"try { print 1; } catch (e) { print 2; }", []string{}},
+		{"var a = 1;\nprint a == a;\nprint a.b < (a.b);\nprint a == b;", []string{
+			"warning 2:9 Comparison of 'a' with itself.",
+			"warning 3:11 Comparison of 'a.b' with itself.",
+			"warning 4:12 Undefined variable 'b'.",
+		}},
+		{"class A { same(o) { return this.x != this.x; } }", []string{
+			"warning 1:16 Parameter 'o' is never used.",
+			"warning 1:35 Comparison of 'this.x' with itself.",
+		}},
+		{"fun f() { count = 1; later = 2; len = 3; }\nvar later;", []string{"warning 1:11 Assignment to undefined variable 'count'."}},
+		{"class A < Base { m() { return helper(later, max); } }\nvar later;", []string{
+			"warning 1:11 Undefined variable 'Base'.",
+			"warning 1:31 Undefined variable 'helper'.",
+		}},
+	}
+	for _, test := range tests {
+		errors := make([]string, 0)
+		tokens := scanner.NewScanner(test.source, func(line int, column int, message string) {
+			errors = append(errors, message)
+		}).ScanTokens()
+		statements, err := parser.NewParser(tokens, func(tok scanner.Token, message string) {
+			errors = append(errors, message)
+		}).Parse()
+		if err != nil || len(errors) != 0 {
+			t.Fatalf("unexpected errors: %v %v (in %v)", err, errors, test.source)
+		}
+		got := Lint(statements, []string{"clock", "len", "max"})
+		if len(got) != len(test.expect) {
+			t.Errorf("expect %v warnings, got: %v (in %q)", len(test.expect), describe(got), test.source)
+			continue
+		}
+		for i, d := range describe(got) {
+			if d != test.expect[i] {
+				t.Errorf("expect: %v, got: %v (in %q)", test.expect[i], d, test.source)
+			}
+		}
+	}
+}
+
+func describe(diagnostics []diagnostic.Diagnostic) []string {
+	described := make([]string, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		described = append(described, fmt.Sprintf("%v %d:%d %v", d.Severity, d.Span.Line, d.Span.Column, d.Message))
+	}
+	return described
+}
@@ -59,24 +59,24 @@ func (l *golox) run(source string) error {
 }
 
 func (l *golox) runtimeError(err *interpreter.RuntimeError) {
-	l.reporter.Error("%v\n[line %d]\n", err.Error(), err.Token.Line)
+	l.reporter.Error("%v\n[line %d:%d]\n", err.Error(), err.Token.Line, err.Token.Column)
 	l.hadRuntimeError = true
 }
 
 func (l *golox) parseError(token scanner.Token, message string) {
 	if token.TokenType == scanner.EOF {
-		l.report(token.Line, " at end", message)
+		l.report(token.Line, token.Column, " at end", message)
 	} else {
-		l.report(token.Line, fmt.Sprintf(" at '%v'", *token.Lexeme), message)
+		l.report(token.Line, token.Column, fmt.Sprintf(" at '%v'", *token.Lexeme), message)
 	}
 }
 
-func (l *golox) error(line int, message string) {
-	l.report(line, "", message)
+func (l *golox) error(line int, column int, message string) {
+	l.report(line, column, "", message)
 }
 
-func (l *golox) report(line int, where string, message string) {
-	l.reporter.Error("[line %d] Error%v: %v\n", line, where, message)
+func (l *golox) report(line int, column int, where string, message string) {
+	l.reporter.Error("[line %d:%d] Error%v: %v\n", line, column, where, message)
 	l.hadError = true
 }
 
@@ -16,8 +16,9 @@ func TestRun(t *testing.T) {
 		{"print 3.3 + 2.2;", []string{"5.5"}, []string{}, false},
 		{"print 4 * 5;", []string{"20"}, []string{}, false},
 		{"print \"hello,\" + \" world!\";", []string{"hello, world!"}, []string{}, false},
-		{"print (3 + 2;", []string{}, []string{"[line 1] Error at ';': expect ')' after expression.\n"}, false},
-		{"print 3 + \"2\";", []string{}, []string{"Operands must be numbers: 2\n[line 1]\n"}, true},
+		{"print (3 + 2;", []string{}, []string{"[line 1:13] Error at ';': expect ')' after expression.\n"}, false},
+		{"print 3 + \"2\";", []string{"32"}, []string{}, false},
+		{"print 3 + true;", []string{}, []string{"Operands must be numbers: true\n[line 1:9]\n"}, true},
 		{"var a = 1; var b = 2; print a + b;", []string{"3"}, []string{}, false},
 		{"var a = 1; {var a = 2; print a;} print a;", []string{"2", "1"}, []string{}, false},
 		{"var i = 1; while(i < 3) {print i; i = i + 1;}", []string{"1", "2"}, []string{}, false},
@@ -32,8 +33,8 @@ func TestRun(t *testing.T) {
 		{"class Greeting {\n\tinit(greeting) {this.greeting = greeting;}\n\t\n\tgreet(name) {\n\t\treturn this.greeting + \" \" + name + \"!\";\n\t}\n}\n\nprint Greeting(\"Hi,\").greet(\"Bob\");", []string{"Hi, Bob!"}, []string{}, false},
 		{"class Doughnut {\n\tcook() {\n\t\tprint \"Fry until golden brown.\";\n\t}\n}\n\nclass BostonCream < Doughnut {}\n\nBostonCream().cook();", []string{"Fry until golden brown."}, []string{}, false},
 		{"class Doughnut {\n\tcook() {\n\t\tprint \"Fry until golden brown.\";\n\t}\n}\n\nclass BostonCream < Doughnut {\n\tcook() {\n\t\tsuper.cook();\n\t\tprint \"Pipe full of custard and coat with chocolate.\";\n\t}\n}\n\nBostonCream().cook();", []string{"Fry until golden brown.", "Pipe full of custard and coat with chocolate."}, []string{}, false},
-		{"super.cook();", []string{}, []string{"[line 1] Error at 'super': Can't use 'super' outside of a class.\n"}, false},
-		{"class BostonCream {\n\tcook() {\n\t\tsuper.cook();\n\t\tprint \"Pipe full of custard and coat with chocolate.\";\n\t}\n}\n\nBostonCream().cook();", []string{}, []string{"[line 3] Error at 'super': Can't use 'super' in a class with no subclass.\n"}, false},
+		{"super.cook();", []string{}, []string{"[line 1:1] Error at 'super': Can't use 'super' outside of a class.\n"}, false},
+		{"class BostonCream {\n\tcook() {\n\t\tsuper.cook();\n\t\tprint \"Pipe full of custard and coat with chocolate.\";\n\t}\n}\n\nBostonCream().cook();", []string{}, []string{"[line 3:3] Error at 'super': Can't use 'super' in a class with no subclass.\n"}, false},
 	}
 
 	for _, test := range tests {
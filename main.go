@@ -2,20 +2,78 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/nesyuk/golox/runtime"
 	"os"
 )
 
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Println(errors.New("usage: glox [script]"))
+	useVM := flag.Bool("vm", false, "run on the bytecode virtual machine")
+	flag.Parse()
+
+	if flag.NArg() > 0 && flag.Arg(0) == "fmt" {
+		format(flag.Args()[1:])
+		return
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "lint" {
+		lint(flag.Args()[1:])
+		return
+	}
+
+	var opts []runtime.Option
+	if *useVM {
+		opts = append(opts, runtime.WithBytecodeVM())
+	}
+	if flag.NArg() > 1 {
+		fmt.Println(errors.New("usage: glox [-vm] [script]"))
 		os.Exit(64)
-	} else if len(os.Args) == 2 {
-		if err := runtime.RunFile(os.Args[1]); err != nil {
+	} else if flag.NArg() == 1 {
+		if err := runtime.RunFile(flag.Arg(0), opts...); err != nil {
 			fmt.Printf("failed to read a file: %v\n", err)
 		}
 	} else {
-		runtime.RunPrompt()
+		runtime.RunPrompt(opts...)
+	}
+}
+
+func format(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list files whose formatting differs and exit with status 1")
+	write := flags.Bool("w", false, "write the result to the source files instead of stdout")
+	flags.Parse(args)
+	if flags.NArg() == 0 || (*check && *write) {
+		fmt.Println(errors.New("usage: glox fmt [-check | -w] file..."))
+		os.Exit(64)
+	}
+
+	mode := runtime.FORMAT_PRINT
+	if *check {
+		mode = runtime.FORMAT_CHECK
+	} else if *write {
+		mode = runtime.FORMAT_WRITE
+	}
+	formatted, err := runtime.FormatFiles(os.Stdout, os.Stderr, mode, flags.Args()...)
+	if err != nil {
+		fmt.Printf("failed to format: %v\n", err)
+		os.Exit(65)
+	}
+	if !formatted && *check {
+		os.Exit(1)
+	}
+}
+
+func lint(paths []string) {
+	if len(paths) == 0 {
+		fmt.Println(errors.New("usage: glox lint file..."))
+		os.Exit(64)
+	}
+	problems, err := runtime.LintFiles(os.Stdout, runtime.ColorEnabled(), paths...)
+	if err != nil {
+		fmt.Printf("failed to lint: %v\n", err)
+		os.Exit(65)
+	}
+	if problems > 0 {
+		os.Exit(1)
 	}
 }
@@ -5,26 +5,34 @@ import (
 	"fmt"
 	"github.com/nesyuk/golox/scanner"
 	"github.com/nesyuk/golox/token"
+	"path/filepath"
+	"strings"
 )
 
 /*
     program -> declaration* EOF
-    declaration -> classDecl | funDecl | varDecl | statement
-    classDecl -> "class" IDENTIFIER ("<" IDENTIFIER)? "{" function* "}"
+    declaration -> classDecl | funDecl | varDecl | importDecl | statement
+    classDecl -> "class" IDENTIFIER ("<" IDENTIFIER)? "{" ("class"? (function | getter))* "}"
     funDecl -> "fun" function
     function -> IDENTIFIER "(" parameters? ")" block
+    getter -> IDENTIFIER block
     parameters -> IDENTIFIER ("," IDENTIFIER)*
     varDecl -> "var" IDENTIFIER ("=" expression)? ";"
-    statement -> exprStmt | printStmt | block
+    importDecl -> "import" ( IDENTIFIER ( "," IDENTIFIER )* "from" )? STRING ";"
+    statement -> exprStmt | forStmt | ifStmt | printStmt | returnStmt | whileStmt | breakStmt | continueStmt | throwStmt | tryStmt | block
     exprStmt -> expression ";"
     forStmt -> "for" "( (varDecl | exprStmt) ";" expr? ";" expr? ")" statement
 	ifStmt ->  "if" "(" expression ")" statement ( "else" statement )?
     printStmt -> "print" expression
     returnStmt -> "return" expression? ";"
 	whileStmt -> "while" "(" expression ")" statement
+    breakStmt -> "break" ";"
+    continueStmt -> "continue" ";"
+    throwStmt -> "throw" expression ";"
+    tryStmt -> "try" block ( "catch" "(" IDENTIFIER ")" block )? ( "finally" block )?
     block -> "{" declaration* "}"
 	expression -> assignment
-    assignment -> (call ".")? IDENTIFIER "=" assignment | logicOr
+    assignment -> (call ".")? IDENTIFIER "=" assignment | call "[" expression "]" "=" assignment | logicOr
 	logicOr -> logicAnd ( "or" logicAnd )*
 	logicAnd-> equality ( "and" equality )*
 	equality -> comparison (("!=" | "==") comparison)*
@@ -32,14 +40,20 @@ import (
 	term -> factor (("-" | "+") factor)*
     factor -> unary (( "/" | "*") unary)*
     unary -> ("!" | "-") unary | call
-    call -> primary ( "(" arguments? ")" | "." IDENTIFIER )*
+    call -> primary ( "(" arguments? ")" | "." IDENTIFIER | "[" expression "]" )*
     arguments -> expression ( "," expression )*
-    primary -> NUMBER | STRING | "true" | "false" | nil | "(" + expression + ")" | IDENTIFIER | "this" | "super" "." IDENTIFIER
+    primary -> NUMBER | STRING | "true" | "false" | nil | "(" + expression + ")" | IDENTIFIER | "this" | "super" "." IDENTIFIER | list | map | lambda | interpolation
+    lambda -> "fun" "(" parameters? ")" block
+    list -> "[" ( expression ( "," expression )* ","? )? "]"
+    map -> "{" ( entry ( "," entry )* ","? )? "}"
+    entry -> expression ":" expression
+    interpolation -> INTERPOLATION expression ( INTERPOLATION expression )* STRING
 */
 
 type Parser struct {
 	tokens        []scanner.Token
 	current       int
+	hadError      bool
 	errorCallback ErrorCallback
 }
 
@@ -47,29 +61,95 @@ func NewParser(tokens []scanner.Token, onError ErrorCallback) *Parser {
 	return &Parser{tokens: tokens, current: 0, errorCallback: onError}
 }
 
+// Parse reports every syntax error through the error callback and returns the statements
+// that were parsed successfully, declarations with errors are skipped.
 func (p *Parser) Parse() ([]token.Stmt, error) {
 	stmts := make([]token.Stmt, 0)
 	for !p.isAtEnd() {
 		stmt, err := p.declaration()
-		if err != nil || stmt == nil {
-			return make([]token.Stmt, 0), err
+		if err != nil {
+			return stmts, err
+		}
+		if stmt != nil {
+			stmts = append(stmts, stmt)
 		}
-		stmts = append(stmts, stmt)
 	}
 	return stmts, nil
 }
 
+// HadError reports whether any syntax error was found.
+func (p *Parser) HadError() bool {
+	return p.hadError
+}
+
 func (p *Parser) declaration() (token.Stmt, error) {
 	if p.match(scanner.CLASS) {
-		return p.class()
-	} else if p.match(scanner.FUN) {
-		return p.function("function")
+		return p.statementSync(p.class())
+	} else if p.check(scanner.FUN) && !p.checkNext(scanner.LEFT_PAREN) {
+		p.advance()
+		return p.statementSync(p.function("function"))
 	} else if p.match(scanner.VAR) {
-		return p.variableDeclaration() //TODO: verify p.statementSync(p.variableDeclaration()) or p.variableDeclaration()
+		return p.statementSync(p.variableDeclaration())
+	} else if p.match(scanner.IMPORT) {
+		return p.statementSync(p.importDeclaration())
 	}
 	return p.statementSync(p.statement())
 }
 
+// importDeclaration binds either the listed names of the module or the whole module
+// to a variable named after the module file.
+func (p *Parser) importDeclaration() (token.Stmt, error) {
+	keyword := p.previous()
+	var names []*scanner.Token
+	if p.check(scanner.IDENTIFIER) {
+		for {
+			name, err := p.consume(scanner.IDENTIFIER, "Expect name to import.")
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+			if !p.match(scanner.COMMA) {
+				break
+			}
+		}
+		// 'from' is not a keyword, it can still name a variable.
+		if !p.check(scanner.IDENTIFIER) || *p.peek().Lexeme != "from" {
+			return nil, p.error(p.peek(), "Expect 'from' after imported names.")
+		}
+		p.advance()
+	}
+	path, err := p.consume(scanner.STRING, "Expect module path.")
+	if err != nil {
+		return nil, err
+	}
+	var name *scanner.Token
+	if names == nil {
+		if name, err = p.moduleName(path); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(scanner.SEMICOLON, "Expect ';' after import."); err != nil {
+		return nil, err
+	}
+	return &token.ImportStmt{Keyword: &keyword, Path: path, Name: name, Names: names}, nil
+}
+
+// moduleName returns an identifier token named after the module file without its extension.
+func (p *Parser) moduleName(path *scanner.Token) (*scanner.Token, error) {
+	base := filepath.Base(path.Literal.(string))
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	tokens := scanner.NewScanner(base, func(int, int, string) {}).ScanTokens()
+	if len(tokens) != 2 || tokens[0].TokenType != scanner.IDENTIFIER || *tokens[0].Lexeme != base {
+		return nil, p.error(*path, fmt.Sprintf("Module name '%v' is not a valid identifier.", base))
+	}
+	name := *path
+	name.TokenType = scanner.IDENTIFIER
+	name.Lexeme = &base
+	return &name, nil
+}
+
+// statementSync recovers from a syntax error by skipping tokens up to the next statement,
+// so parsing can continue and report further errors.
 func (p *Parser) statementSync(stmt token.Stmt, err error) (token.Stmt, error) {
 	var parseErr *ParseError
 	if err != nil && errors.As(err, &parseErr) {
@@ -90,7 +170,7 @@ func (p *Parser) class() (token.Stmt, error) {
 	var supercls *token.VariableExpr
 	if p.match(scanner.LESS) {
 		if _, err = p.consume(scanner.IDENTIFIER, "Expect superclass name"); err != nil {
-			return nil, nil
+			return nil, err
 		}
 		supercls = &token.VariableExpr{Name: p.previous()}
 	}
@@ -98,21 +178,27 @@ func (p *Parser) class() (token.Stmt, error) {
 		return nil, err
 	}
 	methods := make([]*token.FunctionStmt, 0)
+	classMethods := make([]*token.FunctionStmt, 0)
 	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
+		isClassMethod := p.match(scanner.CLASS)
 		stmt, err := p.function("method")
-		method := stmt.(*token.FunctionStmt)
 		if err != nil {
 			return nil, err
 		}
-		methods = append(methods, method)
+		if isClassMethod {
+			classMethods = append(classMethods, stmt.(*token.FunctionStmt))
+		} else {
+			methods = append(methods, stmt.(*token.FunctionStmt))
+		}
 	}
 	if _, err := p.consume(scanner.RIGHT_BRACE, "Expect '}' after class body."); err != nil {
 		return nil, err
 	}
 	return &token.ClassStmt{
-		Name:       name,
-		Superclass: supercls,
-		Methods:    methods,
+		Name:         name,
+		Superclass:   supercls,
+		Methods:      methods,
+		ClassMethods: classMethods,
 	}, nil
 }
 
@@ -121,10 +207,44 @@ func (p *Parser) function(kind string) (token.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
+	if kind == "method" && p.match(scanner.LEFT_BRACE) {
+		return p.getter(tok)
+	}
 	_, err = p.consume(scanner.LEFT_PAREN, fmt.Sprintf(fmt.Sprintf("expect '(' after %v name.", kind)))
 	if err != nil {
 		return nil, err
 	}
+	return p.functionBody(tok, kind)
+}
+
+// getter parses a method without a parameter list, it runs when the property is accessed.
+func (p *Parser) getter(tok *scanner.Token) (token.Stmt, error) {
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	return &token.FunctionStmt{
+		Name:     tok,
+		Params:   make([]*scanner.Token, 0),
+		Body:     body,
+		IsGetter: true,
+	}, nil
+}
+
+// lambda parses an anonymous function, its name is the 'fun' keyword.
+func (p *Parser) lambda() (token.Expr, error) {
+	keyword := p.previous()
+	if _, err := p.consume(scanner.LEFT_PAREN, "expect '(' after 'fun'."); err != nil {
+		return nil, err
+	}
+	fn, err := p.functionBody(&keyword, "function")
+	if err != nil {
+		return nil, err
+	}
+	return &token.LambdaExpr{Function: fn}, nil
+}
+
+func (p *Parser) functionBody(tok *scanner.Token, kind string) (*token.FunctionStmt, error) {
 	params := make([]*scanner.Token, 0)
 	if !p.check(scanner.RIGHT_PAREN) {
 		param, err := p.consume(scanner.IDENTIFIER, fmt.Sprintf("expect %v name.", kind))
@@ -145,10 +265,10 @@ func (p *Parser) function(kind string) (token.Stmt, error) {
 		}
 	}
 
-	if _, err = p.consume(scanner.RIGHT_PAREN, fmt.Sprintf(fmt.Sprintf("expect ')' after parameters."))); err != nil {
+	if _, err := p.consume(scanner.RIGHT_PAREN, fmt.Sprintf(fmt.Sprintf("expect ')' after parameters."))); err != nil {
 		return nil, err
 	}
-	if _, err = p.consume(scanner.LEFT_BRACE, fmt.Sprintf(fmt.Sprintf("expect '{' before %v body.", kind))); err != nil {
+	if _, err := p.consume(scanner.LEFT_BRACE, fmt.Sprintf(fmt.Sprintf("expect '{' before %v body.", kind))); err != nil {
 		return nil, err
 	}
 	body, err := p.block()
@@ -193,6 +313,22 @@ func (p *Parser) statement() (token.Stmt, error) {
 		return p.returnStmt()
 	case p.match(scanner.WHILE):
 		return p.whileStatement()
+	case p.match(scanner.BREAK):
+		keyword := p.previous()
+		if _, err := p.consume(scanner.SEMICOLON, "Expect ';' after 'break'."); err != nil {
+			return nil, err
+		}
+		return &token.BreakStmt{Keyword: &keyword}, nil
+	case p.match(scanner.CONTINUE):
+		keyword := p.previous()
+		if _, err := p.consume(scanner.SEMICOLON, "Expect ';' after 'continue'."); err != nil {
+			return nil, err
+		}
+		return &token.ContinueStmt{Keyword: &keyword}, nil
+	case p.match(scanner.THROW):
+		return p.throwStmt()
+	case p.match(scanner.TRY):
+		return p.tryStmt()
 	case p.match(scanner.LEFT_BRACE):
 		stmts, err := p.block()
 		if err != nil {
@@ -256,11 +392,8 @@ func (p *Parser) forStatement() (token.Stmt, error) {
 		return nil, err
 	}
 
-	if increment != nil {
-		body = &token.BlockStmt{Statements: []token.Stmt{body, &token.ExpressionStmt{Expression: increment}}}
-	}
-
-	body = &token.WhileStmt{Condition: condition, Body: body}
+	// The increment is kept apart from the body, so 'continue' still runs it.
+	body = &token.WhileStmt{Condition: condition, Body: body, Increment: increment}
 
 	if initializer != nil {
 		body = &token.BlockStmt{Statements: []token.Stmt{initializer, body}}
@@ -333,6 +466,59 @@ func (p *Parser) returnStmt() (token.Stmt, error) {
 	}, nil
 }
 
+func (p *Parser) throwStmt() (token.Stmt, error) {
+	keyword := p.previous()
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(scanner.SEMICOLON, "Expect ';' after thrown value."); err != nil {
+		return nil, err
+	}
+	return &token.ThrowStmt{Keyword: &keyword, Value: value}, nil
+}
+
+func (p *Parser) tryStmt() (token.Stmt, error) {
+	keyword := p.previous()
+	if _, err := p.consume(scanner.LEFT_BRACE, "Expect '{' after 'try'."); err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &token.TryStmt{Keyword: &keyword, Body: body}
+	if p.match(scanner.CATCH) {
+		if _, err := p.consume(scanner.LEFT_PAREN, "Expect '(' after 'catch'."); err != nil {
+			return nil, err
+		}
+		if stmt.CatchName, err = p.consume(scanner.IDENTIFIER, "Expect exception variable name."); err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(scanner.RIGHT_PAREN, "Expect ')' after exception variable name."); err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(scanner.LEFT_BRACE, "Expect '{' after catch clause."); err != nil {
+			return nil, err
+		}
+		if stmt.CatchBody, err = p.block(); err != nil {
+			return nil, err
+		}
+	}
+	if p.match(scanner.FINALLY) {
+		if _, err := p.consume(scanner.LEFT_BRACE, "Expect '{' after 'finally'."); err != nil {
+			return nil, err
+		}
+		if stmt.FinallyBody, err = p.block(); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.CatchName == nil && stmt.FinallyBody == nil {
+		return nil, p.error(p.peek(), "Expect 'catch' or 'finally' after try block.")
+	}
+	return stmt, nil
+}
+
 func (p *Parser) printStmt() (token.Stmt, error) {
 	expr, err := p.expression()
 	if err != nil {
@@ -351,7 +537,9 @@ func (p *Parser) block() ([]token.Stmt, error) {
 		if err != nil {
 			return nil, err
 		}
-		stmts = append(stmts, stmt)
+		if stmt != nil {
+			stmts = append(stmts, stmt)
+		}
 	}
 	if _, err := p.consume(scanner.RIGHT_BRACE, "Expect '}' after block."); err != nil {
 		return nil, err
@@ -392,6 +580,8 @@ func (p *Parser) assignment() (token.Expr, error) {
 			return &token.AssignExpr{Name: t.Name, Value: value}, nil
 		case *token.GetExpr:
 			return &token.SetExpr{Object: t.Object, Name: t.Name, Value: value}, nil
+		case *token.IndexExpr:
+			return &token.IndexSetExpr{Object: t.Object, Bracket: t.Bracket, Index: t.Index, Value: value}, nil
 		}
 		return nil, p.error(tokenEquals, "Invalid assignment target.")
 	}
@@ -528,6 +718,16 @@ func (p *Parser) call() (token.Expr, error) {
 				return nil, err
 			}
 			expr = &token.GetExpr{Object: expr, Name: name}
+		} else if p.match(scanner.LEFT_BRACKET) {
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			bracket, err := p.consume(scanner.RIGHT_BRACKET, "Expect ']' after index.")
+			if err != nil {
+				return nil, err
+			}
+			expr = &token.IndexExpr{Object: expr, Bracket: bracket, Index: index}
 		} else {
 			break
 		}
@@ -576,6 +776,8 @@ func (p *Parser) primary() (token.Expr, error) {
 		return &token.LiteralExpr{Value: nil}, nil
 	case p.match(scanner.NUMBER) || p.match(scanner.STRING):
 		return &token.LiteralExpr{Value: p.previous().Literal}, nil
+	case p.match(scanner.INTERPOLATION):
+		return p.interpolation()
 	case p.match(scanner.IDENTIFIER):
 		return &token.VariableExpr{Name: p.previous()}, nil
 	case p.match(scanner.LEFT_PAREN):
@@ -587,6 +789,12 @@ func (p *Parser) primary() (token.Expr, error) {
 			return nil, err
 		}
 		return &token.GroupingExpr{Expression: expr}, err
+	case p.match(scanner.FUN):
+		return p.lambda()
+	case p.match(scanner.LEFT_BRACKET):
+		return p.list()
+	case p.match(scanner.LEFT_BRACE):
+		return p.mapLiteral()
 	case p.match(scanner.SUPER):
 		keyword := p.previous()
 		if _, err := p.consume(scanner.DOT, "Expect '.' after 'super'."); err != nil {
@@ -606,6 +814,75 @@ func (p *Parser) primary() (token.Expr, error) {
 	return nil, p.error(p.peek(), "expect expression")
 }
 
+// interpolation parses the string segments and the expressions between them,
+// the last segment is a string token.
+func (p *Parser) interpolation() (token.Expr, error) {
+	start := p.previous()
+	parts := make([]token.Expr, 0)
+	for {
+		segment := p.previous()
+		if text := segment.Literal.(string); text != "" {
+			parts = append(parts, &token.LiteralExpr{Value: text})
+		}
+		if segment.TokenType == scanner.STRING {
+			return &token.InterpolationExpr{Start: &start, Parts: parts}, nil
+		}
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, expr)
+		if !p.match(scanner.INTERPOLATION, scanner.STRING) {
+			return nil, p.error(p.peek(), "Expect '}' after interpolated expression.")
+		}
+	}
+}
+
+func (p *Parser) list() (token.Expr, error) {
+	bracket := p.previous()
+	elements := make([]token.Expr, 0)
+	for !p.check(scanner.RIGHT_BRACKET) && !p.isAtEnd() {
+		element, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		if !p.match(scanner.COMMA) {
+			break
+		}
+	}
+	if _, err := p.consume(scanner.RIGHT_BRACKET, "Expect ']' after list elements."); err != nil {
+		return nil, err
+	}
+	return &token.ListExpr{Bracket: &bracket, Elements: elements}, nil
+}
+
+func (p *Parser) mapLiteral() (token.Expr, error) {
+	brace := p.previous()
+	keys, values := make([]token.Expr, 0), make([]token.Expr, 0)
+	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
+		key, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(scanner.COLON, "Expect ':' after map key."); err != nil {
+			return nil, err
+		}
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		keys, values = append(keys, key), append(values, value)
+		if !p.match(scanner.COMMA) {
+			break
+		}
+	}
+	if _, err := p.consume(scanner.RIGHT_BRACE, "Expect '}' after map entries."); err != nil {
+		return nil, err
+	}
+	return &token.MapExpr{Brace: &brace, Keys: keys, Values: values}, nil
+}
+
 func (p *Parser) match(tokens ...scanner.TokenType) bool {
 	for _, t := range tokens {
 		if p.check(t) {
@@ -625,6 +902,7 @@ func (p *Parser) consume(tt scanner.TokenType, message string) (*scanner.Token,
 }
 
 func (p *Parser) error(t scanner.Token, message string) error {
+	p.hadError = true
 	p.errorCallback(t, message)
 	return &ParseError{Message: message}
 }
@@ -637,7 +915,7 @@ func (p *Parser) sync() {
 			return
 		}
 		switch p.peek().TokenType {
-		case scanner.CLASS, scanner.FUN, scanner.VAR, scanner.FOR, scanner.IF, scanner.WHILE, scanner.PRINT, scanner.RETURN:
+		case scanner.CLASS, scanner.FUN, scanner.VAR, scanner.IMPORT, scanner.FOR, scanner.IF, scanner.WHILE, scanner.PRINT, scanner.RETURN, scanner.BREAK, scanner.CONTINUE, scanner.THROW, scanner.TRY:
 			return
 		}
 		p.advance()
@@ -651,6 +929,13 @@ func (p *Parser) check(t scanner.TokenType) bool {
 	return p.peek().TokenType == t
 }
 
+func (p *Parser) checkNext(t scanner.TokenType) bool {
+	if p.isAtEnd() || p.current+1 >= len(p.tokens) {
+		return false
+	}
+	return p.tokens[p.current+1].TokenType == t
+}
+
 func (p *Parser) advance() scanner.Token {
 	if !p.isAtEnd() {
 		p.current++
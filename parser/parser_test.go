@@ -233,7 +233,8 @@ func TestParseIfStmtError(t *testing.T) {
 		testCallBack(&errors),
 	)
 	stmts, err := p.Parse()
-	validateHasErrors(t, stmts, errors, err, "expect ')' after if condition")
+	// the parser recovers after 'is true';, so the dangling 'else' is reported as well
+	validateHasErrors(t, stmts, errors, err, "expect ')' after if condition", "expect expression")
 }
 
 func TestParseBlockStmt(t *testing.T) {
@@ -407,10 +408,91 @@ func TestForStmt(t *testing.T) {
 	if !ok {
 		t.Fatalf("expect *token.VarStmt got %T", stmt.Statements[0])
 	}
-	_, ok = stmt.Statements[1].(*token.WhileStmt)
+	while, ok := stmt.Statements[1].(*token.WhileStmt)
 	if !ok {
 		t.Fatalf("expect *token.WhileStmt got %T", stmt.Statements[1])
 	}
+	if _, ok = while.Body.(*token.PrintStmt); !ok {
+		t.Fatalf("expect *token.PrintStmt got %T", while.Body)
+	}
+	if _, ok = while.Increment.(*token.AssignExpr); !ok {
+		t.Fatalf("expect *token.AssignExpr got %T", while.Increment)
+	}
+}
+
+func TestParseRecovery(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// var = 1;
+			testutil.VarDecl(),
+			testutil.Equal(),
+			testutil.Number(1),
+			testutil.Semicolon(),
+			// print "ok";
+			testutil.Print(),
+			testutil.Str("ok"),
+			testutil.Semicolon(),
+			// fun 1() {}
+			testutil.Fun(),
+			testutil.Number(1),
+			testutil.LeftParen(),
+			testutil.RightParen(),
+			testutil.LeftBrace(),
+			testutil.RightBrace(),
+			// var b;
+			testutil.VarDecl(),
+			testutil.Identifier("b"),
+			testutil.Semicolon(),
+			// class { }
+			testutil.Class(),
+			testutil.LeftBrace(),
+			testutil.RightBrace(),
+			testutil.Semicolon(),
+			// { print ; print "in block"; }
+			testutil.LeftBrace(),
+			testutil.Print(),
+			testutil.Semicolon(),
+			testutil.Print(),
+			testutil.Str("in block"),
+			testutil.Semicolon(),
+			testutil.RightBrace(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	if err != nil {
+		t.Fatalf("expect: nil got: %v", err)
+	}
+	if !p.HadError() {
+		t.Fatalf("expect HadError")
+	}
+	expectErrors := []string{"expect variable name", "expect function name.", "Expect class name", "expect expression"}
+	if len(errors) != len(expectErrors) {
+		t.Fatalf("expect %v got %v", expectErrors, errors)
+	}
+	for i := range errors {
+		if errors[i] != expectErrors[i] {
+			t.Fatalf("expect '%v' got '%v'", expectErrors[i], errors[i])
+		}
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("expect len(3) got %v", len(stmts))
+	}
+	if _, ok := stmts[0].(*token.PrintStmt); !ok {
+		t.Fatalf("expect *token.PrintStmt got %T", stmts[0])
+	}
+	if _, ok := stmts[1].(*token.VarStmt); !ok {
+		t.Fatalf("expect *token.VarStmt got %T", stmts[1])
+	}
+	block, ok := stmts[2].(*token.BlockStmt)
+	if !ok {
+		t.Fatalf("expect *token.BlockStmt got %T", stmts[2])
+	}
+	if len(block.Statements) != 1 {
+		t.Fatalf("expect len(1) got %v", len(block.Statements))
+	}
 }
 
 var testCallBack = func(errs *[]string) ErrorCallback {
@@ -442,8 +524,7 @@ func validateHasErrors(t *testing.T, stmts []token.Stmt, errors []string, err er
 	if err != nil {
 		t.Fatalf("expect: nil got: %v", err)
 	}
-	if len(errors) != 1 {
-		t.Log(errors)
+	if len(errors) == 0 {
 		t.Fatalf("expect not empty")
 	}
 	if len(errors) != len(expectErrors) {
@@ -455,3 +536,282 @@ func validateHasErrors(t *testing.T, stmts []token.Stmt, errors []string, err er
 		}
 	}
 }
+
+func TestParseIndexSetExpr(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// xs[0] = [1, 2,];
+			testutil.Identifier("xs"),
+			testutil.LeftBracket(),
+			testutil.Number(0),
+			testutil.RightBracket(),
+			testutil.Equal(),
+			testutil.LeftBracket(),
+			testutil.Number(1),
+			testutil.Comma(),
+			testutil.Number(2),
+			testutil.Comma(),
+			testutil.RightBracket(),
+			testutil.Semicolon(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	validateNoError(t, stmts, errors, err)
+	expr, ok := stmts[0].(*token.ExpressionStmt).Expression.(*token.IndexSetExpr)
+	if !ok {
+		t.Fatalf("expect *token.IndexSetExpr got %T", stmts[0].(*token.ExpressionStmt).Expression)
+	}
+	if object, ok := expr.Object.(*token.VariableExpr); !ok || *object.Name.Lexeme != "xs" {
+		t.Fatalf("expect variable 'xs' got %v", expr.Object)
+	}
+	if _, ok := expr.Index.(*token.LiteralExpr); !ok {
+		t.Fatalf("expect *token.LiteralExpr got %T", expr.Index)
+	}
+	list, ok := expr.Value.(*token.ListExpr)
+	if !ok {
+		t.Fatalf("expect *token.ListExpr got %T", expr.Value)
+	}
+	if len(list.Elements) != 2 {
+		t.Fatalf("expect 2 elements got %d", len(list.Elements))
+	}
+}
+
+func TestParseListExprError(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// [1 2];
+			testutil.LeftBracket(),
+			testutil.Number(1),
+			testutil.Number(2),
+			testutil.RightBracket(),
+			testutil.Semicolon(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	validateHasErrors(t, stmts, errors, err, "Expect ']' after list elements.")
+}
+
+func TestParseMapExpr(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// print {"a": 1, 2: x};
+			testutil.Print(),
+			testutil.LeftBrace(),
+			testutil.Str("a"),
+			testutil.Colon(),
+			testutil.Number(1),
+			testutil.Comma(),
+			testutil.Number(2),
+			testutil.Colon(),
+			testutil.Identifier("x"),
+			testutil.RightBrace(),
+			testutil.Semicolon(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	validateNoError(t, stmts, errors, err)
+	expr, ok := stmts[0].(*token.PrintStmt).Expression.(*token.MapExpr)
+	if !ok {
+		t.Fatalf("expect *token.MapExpr got %T", stmts[0].(*token.PrintStmt).Expression)
+	}
+	if len(expr.Keys) != 2 || len(expr.Values) != 2 {
+		t.Fatalf("expect 2 entries got %d keys and %d values", len(expr.Keys), len(expr.Values))
+	}
+	if _, ok := expr.Values[1].(*token.VariableExpr); !ok {
+		t.Fatalf("expect *token.VariableExpr got %T", expr.Values[1])
+	}
+}
+
+func TestParseMapExprError(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// print {"a" 1};
+			testutil.Print(),
+			testutil.LeftBrace(),
+			testutil.Str("a"),
+			testutil.Number(1),
+			testutil.RightBrace(),
+			testutil.Semicolon(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	validateHasErrors(t, stmts, errors, err, "Expect ':' after map key.")
+}
+
+func TestParseTryStmt(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// try { throw 1; } catch (e) {} finally {}
+			testutil.Try(),
+			testutil.LeftBrace(),
+			testutil.Throw(),
+			testutil.Number(1),
+			testutil.Semicolon(),
+			testutil.RightBrace(),
+			testutil.Catch(),
+			testutil.LeftParen(),
+			testutil.Identifier("e"),
+			testutil.RightParen(),
+			testutil.LeftBrace(),
+			testutil.RightBrace(),
+			testutil.Finally(),
+			testutil.LeftBrace(),
+			testutil.RightBrace(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	validateNoError(t, stmts, errors, err)
+	stmt, ok := stmts[0].(*token.TryStmt)
+	if !ok {
+		t.Fatalf("expect *token.TryStmt got %T", stmts[0])
+	}
+	if _, ok := stmt.Body[0].(*token.ThrowStmt); !ok {
+		t.Fatalf("expect *token.ThrowStmt got %T", stmt.Body[0])
+	}
+	if stmt.CatchName == nil || *stmt.CatchName.Lexeme != "e" {
+		t.Fatalf("expect catch variable 'e' got %v", stmt.CatchName)
+	}
+	if stmt.CatchBody == nil || stmt.FinallyBody == nil {
+		t.Fatalf("expect catch and finally clauses")
+	}
+}
+
+func TestParseLambdaExpr(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// var f = fun (a) { return a; };
+			testutil.VarDecl(),
+			testutil.Identifier("f"),
+			testutil.Equal(),
+			testutil.Fun(),
+			testutil.LeftParen(),
+			testutil.Identifier("a"),
+			testutil.RightParen(),
+			testutil.LeftBrace(),
+			*testutil.Return(),
+			testutil.Identifier("a"),
+			testutil.Semicolon(),
+			testutil.RightBrace(),
+			testutil.Semicolon(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	validateNoError(t, stmts, errors, err)
+	lambda, ok := stmts[0].(*token.VarStmt).Initializer.(*token.LambdaExpr)
+	if !ok {
+		t.Fatalf("expect *token.LambdaExpr got %T", stmts[0].(*token.VarStmt).Initializer)
+	}
+	if len(lambda.Function.Params) != 1 || len(lambda.Function.Body) != 1 {
+		t.Fatalf("expect 1 parameter and 1 statement got %v and %v", len(lambda.Function.Params), len(lambda.Function.Body))
+	}
+}
+
+func TestParseClassMethods(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// class A { init() {} class create() {} }
+			testutil.Class(),
+			testutil.Identifier("A"),
+			testutil.LeftBrace(),
+			testutil.Identifier("init"),
+			testutil.LeftParen(),
+			testutil.RightParen(),
+			testutil.LeftBrace(),
+			testutil.RightBrace(),
+			testutil.Class(),
+			testutil.Identifier("create"),
+			testutil.LeftParen(),
+			testutil.RightParen(),
+			testutil.LeftBrace(),
+			testutil.RightBrace(),
+			testutil.RightBrace(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	validateNoError(t, stmts, errors, err)
+	class := stmts[0].(*token.ClassStmt)
+	if len(class.Methods) != 1 || *class.Methods[0].Name.Lexeme != "init" {
+		t.Fatalf("expect method 'init' got %v", class.Methods)
+	}
+	if len(class.ClassMethods) != 1 || *class.ClassMethods[0].Name.Lexeme != "create" {
+		t.Fatalf("expect class method 'create' got %v", class.ClassMethods)
+	}
+}
+
+func TestParseGetter(t *testing.T) {
+	errors := make([]string, 0)
+	p := NewParser(
+		[]scanner.Token{
+			// class A { area { return 1; } }
+			testutil.Class(),
+			testutil.Identifier("A"),
+			testutil.LeftBrace(),
+			testutil.Identifier("area"),
+			testutil.LeftBrace(),
+			*testutil.Return(),
+			testutil.Number(1),
+			testutil.Semicolon(),
+			testutil.RightBrace(),
+			testutil.RightBrace(),
+			testutil.Eof(),
+		},
+		testCallBack(&errors),
+	)
+	stmts, err := p.Parse()
+	validateNoError(t, stmts, errors, err)
+	getter := stmts[0].(*token.ClassStmt).Methods[0]
+	if !getter.IsGetter || len(getter.Params) != 0 || len(getter.Body) != 1 {
+		t.Fatalf("expect getter 'area' got %v", getter)
+	}
+}
+
+func TestParseImportStmt(t *testing.T) {
+	for _, test := range []struct {
+		tokens []scanner.Token
+		name   string
+		names  []string
+	}{
+		// import "lib/math.lox";
+		{[]scanner.Token{testutil.Import(), testutil.Str("lib/math.lox"), testutil.Semicolon(), testutil.Eof()}, "math", nil},
+		// import min, max from "lib/math.lox";
+		{[]scanner.Token{testutil.Import(), testutil.Identifier("min"), testutil.Comma(), testutil.Identifier("max"),
+			testutil.Identifier("from"), testutil.Str("lib/math.lox"), testutil.Semicolon(), testutil.Eof()}, "", []string{"min", "max"}},
+	} {
+		errors := make([]string, 0)
+		stmts, err := NewParser(test.tokens, testCallBack(&errors)).Parse()
+		validateNoError(t, stmts, errors, err)
+		stmt := stmts[0].(*token.ImportStmt)
+		if test.name != "" && (stmt.Name == nil || *stmt.Name.Lexeme != test.name) {
+			t.Fatalf("expect module bound to '%v' got %v", test.name, stmt.Name)
+		}
+		if len(stmt.Names) != len(test.names) {
+			t.Fatalf("expect names %v got %v", test.names, stmt.Names)
+		}
+		for idx, name := range test.names {
+			if *stmt.Names[idx].Lexeme != name {
+				t.Fatalf("expect name '%v' got '%v'", name, *stmt.Names[idx].Lexeme)
+			}
+		}
+	}
+}
@@ -1,21 +1,59 @@
 package resolver
 
 import (
-	"github.com/nesyuk/golox/interpreter"
+	"fmt"
 	"github.com/nesyuk/golox/scanner"
 	"github.com/nesyuk/golox/token"
 )
 
+// UndefinedCheck tells which references to undefined globals are reported.
+type UndefinedCheck uint8
+
+const (
+	// CHECK_ALL reports every reference, the whole program is resolved at once.
+	CHECK_ALL UndefinedCheck = iota
+	// CHECK_TOP_LEVEL reports the references outside of functions, for input resolved
+	// entry by entry: a function may use a global defined by a later entry.
+	CHECK_TOP_LEVEL
+	// CHECK_NONE reports nothing, e.g. when the linter reports them itself.
+	CHECK_NONE
+)
+
+// Interpreter is told the scope distance of every local variable reference,
+// it is implemented by the tree-walking interpreter and by the bytecode compiler.
+type Interpreter interface {
+	Resolve(expr token.Expr, depth int)
+}
+
 type Resolver struct {
-	scopes        []map[string]bool
-	currentFn     FunctionType
-	currentCls    ClassType
-	interpreter   *interpreter.Interpreter
+	scopes []map[string]bool
+	// Globals the program can reference: top-level declarations and the ones declared by DeclareGlobals.
+	globals    map[string]bool
+	check      UndefinedCheck
+	currentFn  FunctionType
+	currentCls ClassType
+	// Number of loops enclosing the current statement within the current function.
+	loopDepth     int
+	interpreter   Interpreter
 	errorCallback ErrorCallback
 }
 
-func New(i *interpreter.Interpreter, onError ErrorCallback) *Resolver {
-	return &Resolver{make([]map[string]bool, 0), FN_NONE, CLS_NONE, i, onError}
+func New(i Interpreter, onError ErrorCallback) *Resolver {
+	return &Resolver{make([]map[string]bool, 0), make(map[string]bool), CHECK_ALL, FN_NONE, CLS_NONE, 0, i, onError}
+}
+
+// CheckUndefined sets which references to undefined globals are reported, CHECK_ALL by default.
+func (r *Resolver) CheckUndefined(check UndefinedCheck) *Resolver {
+	r.check = check
+	return r
+}
+
+// DeclareGlobals adds globals defined outside of the resolved statements: the built-in functions,
+// the definitions of previous REPL entries or of the Go program embedding Lox.
+func (r *Resolver) DeclareGlobals(names ...string) {
+	for _, name := range names {
+		r.globals[name] = true
+	}
 }
 
 func (r *Resolver) beginScope() {
@@ -34,13 +72,34 @@ func (r *Resolver) resolveExpr(expr token.Expr) (interface{}, error) {
 	return expr.Accept(r)
 }
 
-func (r *Resolver) resolveLocal(expr token.Expr, name scanner.Token) {
+// resolveLocal reports whether the name is a local variable.
+func (r *Resolver) resolveLocal(expr token.Expr, name scanner.Token) bool {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
 		if _, exist := r.scopes[i][*name.Lexeme]; exist {
 			r.interpreter.Resolve(expr, len(r.scopes)-1-i)
-			return
+			return true
+		}
+	}
+	return false
+}
+
+// resolveVariable reports a variable that is neither a local nor a global of the program.
+func (r *Resolver) resolveVariable(expr token.Expr, name scanner.Token) {
+	if r.resolveLocal(expr, name) || r.globals[*name.Lexeme] {
+		return
+	}
+	if r.check == CHECK_ALL || r.check == CHECK_TOP_LEVEL && r.currentFn == FN_NONE {
+		r.errorCallback(name, fmt.Sprintf("Undefined variable '%v'.", *name.Lexeme))
+	}
+}
+
+func (r *Resolver) inScope(name scanner.Token) bool {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, exist := r.scopes[i][*name.Lexeme]; exist {
+			return true
 		}
 	}
+	return false
 }
 
 func (r *Resolver) declare(name *scanner.Token) {
@@ -63,6 +122,9 @@ func (r *Resolver) define(name *scanner.Token) {
 }
 
 func (r *Resolver) Resolve(stmts []token.Stmt) (interface{}, error) {
+	if len(r.scopes) == 0 {
+		r.DeclareGlobals(TopLevelNames(stmts)...)
+	}
 	for _, s := range stmts {
 		if _, err := r.resolveStmt(s); err != nil {
 			return nil, err
@@ -71,11 +133,35 @@ func (r *Resolver) Resolve(stmts []token.Stmt) (interface{}, error) {
 	return nil, nil
 }
 
+// TopLevelNames returns the globals declared by the statements of a program. Globals can be used
+// before they are declared, e.g. in a function declared first.
+func TopLevelNames(stmts []token.Stmt) []string {
+	names := make([]string, 0)
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *token.VarStmt:
+			names = append(names, *s.Name.Lexeme)
+		case *token.FunctionStmt:
+			names = append(names, *s.Name.Lexeme)
+		case *token.ClassStmt:
+			names = append(names, *s.Name.Lexeme)
+		case *token.ImportStmt:
+			if s.Name != nil {
+				names = append(names, *s.Name.Lexeme)
+			}
+			for _, name := range s.Names {
+				names = append(names, *name.Lexeme)
+			}
+		}
+	}
+	return names
+}
+
 func (r *Resolver) VisitAssignExpr(expr *token.AssignExpr) (interface{}, error) {
 	if _, err := r.resolveExpr(expr.Value); err != nil {
 		return nil, err
 	}
-	r.resolveLocal(expr, expr.Name)
+	r.resolveVariable(expr, expr.Name)
 	return nil, nil
 }
 
@@ -102,6 +188,10 @@ func (r *Resolver) VisitSuperExpr(expr *token.SuperExpr) (interface{}, error) {
 		r.errorCallback(expr.Keyword, "Can't use 'super' in a class with no subclass.")
 		return nil, nil
 	}
+	if !r.inScope(expr.Keyword) {
+		r.errorCallback(expr.Keyword, "Can't use 'super' in a class method.")
+		return nil, nil
+	}
 
 	r.resolveLocal(expr, expr.Keyword)
 	return nil, nil
@@ -112,6 +202,10 @@ func (r *Resolver) VisitThisExpr(expr *token.ThisExpr) (interface{}, error) {
 		r.errorCallback(expr.Keyword, "Can't use 'this' outside of a class.")
 		return nil, nil
 	}
+	if !r.inScope(expr.Keyword) {
+		r.errorCallback(expr.Keyword, "Can't use 'this' in a class method.")
+		return nil, nil
+	}
 	r.resolveLocal(expr, expr.Keyword)
 	return nil, nil
 }
@@ -157,7 +251,7 @@ func (r *Resolver) VisitVariableExpr(expr *token.VariableExpr) (interface{}, err
 			return nil, nil
 		}
 	}
-	r.resolveLocal(expr, expr.Name)
+	r.resolveVariable(expr, expr.Name)
 	return nil, nil
 }
 
@@ -175,6 +269,57 @@ func (r *Resolver) VisitGroupingExpr(expr *token.GroupingExpr) (interface{}, err
 	return r.resolveExpr(expr.Expression)
 }
 
+func (r *Resolver) VisitLambdaExpr(expr *token.LambdaExpr) (interface{}, error) {
+	return r.resolveFunction(expr.Function, FUNCTION)
+}
+
+func (r *Resolver) VisitListExpr(expr *token.ListExpr) (interface{}, error) {
+	for _, element := range expr.Elements {
+		if _, err := r.resolveExpr(element); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitInterpolationExpr(expr *token.InterpolationExpr) (interface{}, error) {
+	for _, part := range expr.Parts {
+		if _, err := r.resolveExpr(part); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitMapExpr(expr *token.MapExpr) (interface{}, error) {
+	for idx := range expr.Keys {
+		if _, err := r.resolveExpr(expr.Keys[idx]); err != nil {
+			return nil, err
+		}
+		if _, err := r.resolveExpr(expr.Values[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitIndexExpr(expr *token.IndexExpr) (interface{}, error) {
+	if _, err := r.resolveExpr(expr.Object); err != nil {
+		return nil, err
+	}
+	return r.resolveExpr(expr.Index)
+}
+
+func (r *Resolver) VisitIndexSetExpr(expr *token.IndexSetExpr) (interface{}, error) {
+	if _, err := r.resolveExpr(expr.Value); err != nil {
+		return nil, err
+	}
+	if _, err := r.resolveExpr(expr.Object); err != nil {
+		return nil, err
+	}
+	return r.resolveExpr(expr.Index)
+}
+
 func (r *Resolver) VisitBlockStmt(stmt *token.BlockStmt) (interface{}, error) {
 	r.beginScope()
 	if _, err := r.Resolve(stmt.Statements); err != nil {
@@ -212,6 +357,9 @@ func (r *Resolver) VisitClassStmt(stmt *token.ClassStmt) (interface{}, error) {
 	for _, met := range stmt.Methods {
 		declaration := METHOD
 		if *met.Name.Lexeme == "init" {
+			if met.IsGetter {
+				r.errorCallback(*met.Name, "Can't use 'init' as a getter.")
+			}
 			declaration = INITIALIZER
 		}
 		if _, err := r.resolveFunction(met, declaration); err != nil {
@@ -225,6 +373,13 @@ func (r *Resolver) VisitClassStmt(stmt *token.ClassStmt) (interface{}, error) {
 		r.endScope()
 	}
 
+	// Class methods are resolved outside of the 'this' and 'super' scopes.
+	for _, met := range stmt.ClassMethods {
+		if _, err := r.resolveFunction(met, METHOD); err != nil {
+			return nil, err
+		}
+	}
+
 	r.currentCls = enclosingCls
 	return nil, nil
 }
@@ -240,8 +395,8 @@ func (r *Resolver) VisitFunctionStmt(stmt *token.FunctionStmt) (interface{}, err
 }
 
 func (r *Resolver) resolveFunction(stmt *token.FunctionStmt, fnType FunctionType) (interface{}, error) {
-	enclosingFn := r.currentFn
-	r.currentFn = fnType
+	enclosingFn, enclosingLoopDepth := r.currentFn, r.loopDepth
+	r.currentFn, r.loopDepth = fnType, 0
 	r.beginScope()
 	for _, param := range stmt.Params {
 		r.declare(param)
@@ -251,7 +406,7 @@ func (r *Resolver) resolveFunction(stmt *token.FunctionStmt, fnType FunctionType
 		return nil, err
 	}
 	r.endScope()
-	r.currentFn = enclosingFn
+	r.currentFn, r.loopDepth = enclosingFn, enclosingLoopDepth
 	return nil, nil
 }
 
@@ -289,13 +444,77 @@ func (r *Resolver) VisitReturnStmt(stmt *token.ReturnStmt) (interface{}, error)
 	return nil, nil
 }
 
+func (r *Resolver) VisitImportStmt(stmt *token.ImportStmt) (interface{}, error) {
+	if stmt.Name != nil {
+		r.declare(stmt.Name)
+		r.define(stmt.Name)
+	}
+	for _, name := range stmt.Names {
+		r.declare(name)
+		r.define(name)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitThrowStmt(stmt *token.ThrowStmt) (interface{}, error) {
+	return r.resolveExpr(stmt.Value)
+}
+
+func (r *Resolver) VisitTryStmt(stmt *token.TryStmt) (interface{}, error) {
+	if _, err := r.resolveBlock(stmt.Body, nil); err != nil {
+		return nil, err
+	}
+	if stmt.CatchName != nil {
+		if _, err := r.resolveBlock(stmt.CatchBody, stmt.CatchName); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.FinallyBody != nil {
+		return r.resolveBlock(stmt.FinallyBody, nil)
+	}
+	return nil, nil
+}
+
+// resolveBlock resolves statements in a new scope, which declares the variable when it is not nil.
+func (r *Resolver) resolveBlock(stmts []token.Stmt, variable *scanner.Token) (interface{}, error) {
+	r.beginScope()
+	if variable != nil {
+		r.declare(variable)
+		r.define(variable)
+	}
+	if _, err := r.Resolve(stmts); err != nil {
+		return nil, err
+	}
+	r.endScope()
+	return nil, nil
+}
+
 func (r *Resolver) VisitWhileStmt(stmt *token.WhileStmt) (interface{}, error) {
 	if _, err := r.resolveExpr(stmt.Condition); err != nil {
 		return nil, err
 	}
+	r.loopDepth++
 	if _, err := r.resolveStmt(stmt.Body); err != nil {
 		return nil, err
 	}
+	r.loopDepth--
+	if stmt.Increment != nil {
+		return r.resolveExpr(stmt.Increment)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitBreakStmt(stmt *token.BreakStmt) (interface{}, error) {
+	if r.loopDepth == 0 {
+		r.errorCallback(*stmt.Keyword, "Can't use 'break' outside of a loop.")
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitContinueStmt(stmt *token.ContinueStmt) (interface{}, error) {
+	if r.loopDepth == 0 {
+		r.errorCallback(*stmt.Keyword, "Can't use 'continue' outside of a loop.")
+	}
 	return nil, nil
 }
 
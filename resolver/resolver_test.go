@@ -9,6 +9,7 @@ import (
 )
 
 func TestResolver_Resolve(t *testing.T) {
+	tokF := testutil.Identifier("f")
 	tests := []struct {
 		stmts       []token.Stmt
 		staticErrs  []string
@@ -24,13 +25,32 @@ func TestResolver_Resolve(t *testing.T) {
 		{
 			[]token.Stmt{
 				&token.VarStmt{
-					Name:        scanner.Token{},
+					Name:        testutil.Identifier("a"),
 					Initializer: nil,
 				},
 			},
 			[]string{},
 			[]string{},
 		},
+		{
+			[]token.Stmt{
+				&token.PrintStmt{Expression: &token.VariableExpr{Name: testutil.Identifier("a")}},
+				&token.ExpressionStmt{Expression: &token.AssignExpr{Name: testutil.Identifier("b"), Value: &token.LiteralExpr{Value: 1.0}}},
+				&token.PrintStmt{Expression: &token.CallExpr{Callee: &token.VariableExpr{Name: testutil.Identifier("clock")}}},
+			},
+			[]string{"Undefined variable 'a'.", "Undefined variable 'b'."},
+			[]string{},
+		},
+		{
+			[]token.Stmt{
+				&token.FunctionStmt{Name: &tokF, Body: []token.Stmt{
+					&token.PrintStmt{Expression: &token.VariableExpr{Name: testutil.Identifier("a")}},
+				}},
+				&token.VarStmt{Name: testutil.Identifier("a")},
+			},
+			[]string{},
+			[]string{},
+		},
 	}
 	for _, test := range tests {
 		runtimeErrs := make([]string, 0)
@@ -38,6 +58,7 @@ func TestResolver_Resolve(t *testing.T) {
 		interpr := interpreter.New(reporter.onError, reporter.onPrint)
 		staticErrs := make([]string, 0)
 		res := New(interpr, testCallBack(&staticErrs))
+		res.DeclareGlobals("clock")
 		res.Resolve(test.stmts)
 		checkErrors(t, test.runtimeErrs, runtimeErrs)
 		checkErrors(t, test.staticErrs, staticErrs)
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nesyuk/golox/formatter"
+	"io"
+	"os"
+)
+
+type FormatMode uint8
+
+const (
+	// FORMAT_PRINT writes the formatted files to the output.
+	FORMAT_PRINT FormatMode = iota
+	// FORMAT_CHECK lists the files that are not formatted, nothing is changed.
+	FORMAT_CHECK
+	// FORMAT_WRITE replaces the files with their formatted source.
+	FORMAT_WRITE
+)
+
+// FormatFiles formats Lox files in the given mode and reports whether all of them were already formatted.
+// Files with syntax errors are reported to the error output and left unchanged.
+func FormatFiles(out io.Writer, errOut io.Writer, mode FormatMode, paths ...string) (bool, error) {
+	formatted, failed := true, false
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		result, err := formatter.Format(string(source))
+		var syntaxErr *formatter.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			for _, d := range syntaxErr.Diagnostics {
+				fmt.Fprintf(errOut, "%v:%d:%d: %v\n", path, d.Span.Line, d.Span.Column, d.Message)
+			}
+			failed = true
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		if result == string(source) {
+			if mode == FORMAT_PRINT {
+				fmt.Fprint(out, result)
+			}
+			continue
+		}
+		formatted = false
+		switch mode {
+		case FORMAT_PRINT:
+			fmt.Fprint(out, result)
+		case FORMAT_CHECK:
+			fmt.Fprintln(out, path)
+		case FORMAT_WRITE:
+			if err := os.WriteFile(path, []byte(result), 0o644); err != nil {
+				return false, err
+			}
+		}
+	}
+	if failed {
+		return false, errors.New("some files have syntax errors")
+	}
+	return formatted, nil
+}
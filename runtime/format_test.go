@@ -0,0 +1,54 @@
+package runtime
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatFiles(t *testing.T) {
+	dir := t.TempDir()
+	formatted, messy, broken := filepath.Join(dir, "a.lox"), filepath.Join(dir, "b.lox"), filepath.Join(dir, "c.lox")
+	for path, source := range map[string]string{formatted: "print 1;\n", messy: "print   2 ;", broken: "print (;"} {
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	if ok, err := FormatFiles(out, errOut, FORMAT_CHECK, formatted, messy); ok || err != nil {
+		t.Errorf("expect unformatted files without an error, got: %v, %v", ok, err)
+	}
+	if out.String() != messy+"\n" {
+		t.Errorf("expect only %v to be listed, got: %q", messy, out.String())
+	}
+
+	out.Reset()
+	if ok, err := FormatFiles(out, errOut, FORMAT_PRINT, formatted, messy); ok || err != nil {
+		t.Errorf("expect unformatted files without an error, got: %v, %v", ok, err)
+	}
+	if out.String() != "print 1;\nprint 2;\n" {
+		t.Errorf("expect formatted sources, got: %q", out.String())
+	}
+
+	if _, err := FormatFiles(out, errOut, FORMAT_WRITE, messy); err != nil {
+		t.Fatal(err)
+	}
+	if source, _ := os.ReadFile(messy); string(source) != "print 2;\n" {
+		t.Errorf("expect the file to be rewritten, got: %q", source)
+	}
+	if ok, err := FormatFiles(out, errOut, FORMAT_CHECK, formatted, messy); !ok || err != nil {
+		t.Errorf("expect formatted files, got: %v, %v", ok, err)
+	}
+
+	if _, err := FormatFiles(out, errOut, FORMAT_WRITE, broken); err == nil {
+		t.Error("expect an error for a file with syntax errors")
+	}
+	if expect := broken + ":1:8: expect expression\n"; errOut.String() != expect {
+		t.Errorf("expect %q, got: %q", expect, errOut.String())
+	}
+	if source, _ := os.ReadFile(broken); string(source) != "print (;" {
+		t.Errorf("expect the file to be unchanged, got: %q", source)
+	}
+}
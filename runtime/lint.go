@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"fmt"
+	"github.com/nesyuk/golox/diagnostic"
+	"github.com/nesyuk/golox/interpreter"
+	"github.com/nesyuk/golox/lint"
+	"github.com/nesyuk/golox/parser"
+	"github.com/nesyuk/golox/resolver"
+	"github.com/nesyuk/golox/scanner"
+	"github.com/nesyuk/golox/token"
+	"io"
+	"os"
+)
+
+// unresolved ignores the scope distances, linting doesn't run the program.
+type unresolved struct{}
+
+func (unresolved) Resolve(token.Expr, int) {}
+
+// LintFiles reports the errors and the lint warnings of Lox files to the output, files with errors are not linted.
+// It returns the number of errors and warnings, notes are not counted.
+func LintFiles(out io.Writer, color bool, paths ...string) (int, error) {
+	problems := 0
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return problems, err
+		}
+		var diagnostics []diagnostic.Diagnostic
+		compileError := func(tok scanner.Token, message string) {
+			diagnostics = append(diagnostics, diagnostic.Diagnostic{Severity: diagnostic.ERROR, Span: diagnostic.TokenSpan(tok), Message: message})
+		}
+		tokens := scanner.NewScanner(string(source), func(line int, column int, message string) {
+			diagnostics = append(diagnostics, diagnostic.Diagnostic{Severity: diagnostic.ERROR, Span: diagnostic.PointSpan(line, column), Message: message})
+		}).ScanTokens()
+		statements, err := parser.NewParser(tokens, compileError).Parse()
+		if err != nil {
+			return problems, err
+		}
+		if len(diagnostics) == 0 {
+			// Undefined globals are reported by the linter, so they don't hide the other warnings.
+			resolver.New(unresolved{}, compileError).CheckUndefined(resolver.CHECK_NONE).Resolve(statements)
+		}
+		if len(diagnostics) == 0 {
+			diagnostics = lint.Lint(statements, interpreter.NativeNames())
+		}
+
+		renderer := diagnostic.NewRenderer(string(source), path, color)
+		for _, d := range diagnostics {
+			fmt.Fprint(out, renderer.Render(d))
+			if d.Severity != diagnostic.NOTE {
+				problems++
+			}
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,42 @@
+package runtime
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintFiles(t *testing.T) {
+	dir := t.TempDir()
+	clean, warned, broken := filepath.Join(dir, "a.lox"), filepath.Join(dir, "b.lox"), filepath.Join(dir, "c.lox")
+	for path, source := range map[string]string{
+		clean:  "fun f(x) { return x; }\nprint f(1);",
+		warned: "fun f(x) {\n  var y = 1;\n  missing = 2;\n  return 1;\n}",
+		broken: "return 1;",
+	} {
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if problems, err := LintFiles(out, false, clean); problems != 0 || err != nil || out.Len() != 0 {
+		t.Errorf("expect no problems, got: %v, %v, %q", problems, err, out.String())
+	}
+
+	problems, err := LintFiles(out, false, warned, broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems != 4 {
+		t.Errorf("expect three warnings and an error, got: %v", problems)
+	}
+	expect := "warning: Parameter 'x' is never used.\n --> " + warned + ":1:7\n  |\n1 | fun f(x) {\n  |       ^\n" +
+		"warning: Local variable 'y' is never used.\n --> " + warned + ":2:7\n  |\n2 |   var y = 1;\n  |       ^\n" +
+		"warning: Assignment to undefined variable 'missing'.\n --> " + warned + ":3:3\n  |\n3 |   missing = 2;\n  |   ^~~~~~~\n" +
+		"error: Can't return from top-level code.\n --> " + broken + ":1:1\n  |\n1 | return 1;\n  | ^~~~~~\n"
+	if out.String() != expect {
+		t.Errorf("expect:\n%v\ngot:\n%v", expect, out.String())
+	}
+}
@@ -1,37 +1,79 @@
 package runtime
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"github.com/nesyuk/golox/diagnostic"
 	"github.com/nesyuk/golox/interpreter"
 	"github.com/nesyuk/golox/parser"
 	"github.com/nesyuk/golox/resolver"
 	"github.com/nesyuk/golox/scanner"
-	"io"
+	"github.com/nesyuk/golox/token"
+	"github.com/nesyuk/golox/vm"
+	"io/fs"
 	"os"
 )
 
 type golox struct {
+	// The backend is created by the first run and kept for the following ones,
+	// so definitions of one REPL entry are visible to the next.
 	interpret       *interpreter.Interpreter
+	machine         *vm.VM
 	reporter        Reporter
 	hadError        bool
 	hadRuntimeError bool
+	// When set, errors are rendered with source snippets instead of a single line.
+	diagnostics *diagnosticOptions
+	renderer    *diagnostic.Renderer
+	useVM       bool
+	// Path of the program, imports are relative to it.
+	filename string
+	// Renderers of imported files by path, the tokens of a module record its path.
+	moduleRenderers map[string]*diagnostic.Renderer
+	// When set, the value of a trailing expression statement is printed.
+	echo bool
+	// Which references to undefined globals are compile errors, the REPL leaves
+	// function bodies to the runtime: a later entry may define the globals they use.
+	undefinedCheck resolver.UndefinedCheck
 }
 
-func newLox() *golox {
-	return &golox{&interpreter.Interpreter{}, &StdoutReporter{}, false, false}
+type Option func(*golox)
+
+// WithBytecodeVM runs programs on the bytecode virtual machine instead of the tree-walking interpreter.
+func WithBytecodeVM() Option {
+	return func(l *golox) {
+		l.useVM = true
+	}
 }
 
-func NewLox(reporter Reporter) *golox {
-	return &golox{&interpreter.Interpreter{}, reporter, false, false}
+type diagnosticOptions struct {
+	filename string
+	color    bool
 }
 
-func (l *golox) run(source string) error {
-	if l.hadError {
-		os.Exit(65)
+func newLox(filename string, opts ...Option) *golox {
+	l := &golox{
+		reporter:    &StdoutReporter{},
+		diagnostics: &diagnosticOptions{filename, ColorEnabled()},
+		filename:    filename,
 	}
-	if l.hadRuntimeError {
-		os.Exit(70)
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func NewLox(reporter Reporter, opts ...Option) *golox {
+	l := &golox{reporter: reporter}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *golox) run(source string) error {
+	if l.diagnostics != nil {
+		l.renderer = diagnostic.NewRenderer(source, l.diagnostics.filename, l.diagnostics.color)
 	}
 	sc := scanner.NewScanner(source, l.error)
 	tokens := sc.ScanTokens()
@@ -41,10 +83,17 @@ func (l *golox) run(source string) error {
 	if err != nil || l.hadError {
 		return err
 	}
+	if l.echo {
+		statements = echoLast(statements)
+	}
 
-	i := interpreter.New(l.runtimeError, l.reporter.Print)
+	if l.useVM {
+		return l.runVM(statements)
+	}
 
-	res := resolver.New(i, l.parseError)
+	i := l.interpreter()
+	res := resolver.New(i, l.parseError).CheckUndefined(l.undefinedCheck)
+	res.DeclareGlobals(i.GlobalNames()...)
 	res.Resolve(statements)
 
 	if l.hadError {
@@ -58,57 +107,176 @@ func (l *golox) run(source string) error {
 	return nil
 }
 
+func (l *golox) runVM(statements []token.Stmt) error {
+	machine := l.vm()
+	compiler := vm.NewCompiler(l.parseError)
+	res := resolver.New(compiler, l.parseError).CheckUndefined(l.undefinedCheck)
+	res.DeclareGlobals(machine.GlobalNames()...)
+	res.Resolve(statements)
+	if l.hadError {
+		return nil
+	}
+
+	fn, err := compiler.Compile(statements)
+	if err != nil || l.hadError {
+		return err
+	}
+	return machine.Interpret(fn)
+}
+
+func (l *golox) interpreter() *interpreter.Interpreter {
+	if l.interpret != nil {
+		return l.interpret
+	}
+	i := interpreter.New(l.runtimeError, l.reporter.Print)
+	i.SetModuleLoader(l.filename, func(path string) ([]token.Stmt, error) {
+		var statements []token.Stmt
+		err := l.compileModule(path, func(stmts []token.Stmt) error {
+			res := resolver.New(i, l.parseError)
+			res.DeclareGlobals(interpreter.NativeNames()...)
+			res.Resolve(stmts)
+			statements = stmts
+			return nil
+		})
+		return statements, err
+	})
+	l.interpret = i
+	return i
+}
+
+func (l *golox) vm() *vm.VM {
+	if l.machine != nil {
+		return l.machine
+	}
+	machine := vm.New(l.runtimeError, l.reporter.Print)
+	machine.SetModuleLoader(l.filename, func(path string) (*vm.Function, error) {
+		var module *vm.Function
+		err := l.compileModule(path, func(stmts []token.Stmt) error {
+			moduleCompiler := vm.NewCompiler(l.parseError)
+			res := resolver.New(moduleCompiler, l.parseError)
+			res.DeclareGlobals(interpreter.NativeNames()...)
+			res.Resolve(stmts)
+			if l.hadError {
+				return nil
+			}
+			var err error
+			module, err = moduleCompiler.Compile(stmts)
+			return err
+		})
+		return module, err
+	})
+	l.machine = machine
+	return machine
+}
+
+// compileModule reads and parses an imported file and passes it to the backend,
+// errors are reported against the source of the module.
+func (l *golox) compileModule(path string, compile func([]token.Stmt) error) error {
+	source, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return errors.New("no such file")
+	} else if err != nil {
+		return err
+	}
+	renderer, hadError := l.renderer, l.hadError
+	defer func() {
+		l.renderer, l.hadError = renderer, hadError
+	}()
+	if l.diagnostics != nil {
+		l.renderer = diagnostic.NewRenderer(string(source), path, l.diagnostics.color)
+		if l.moduleRenderers == nil {
+			l.moduleRenderers = make(map[string]*diagnostic.Renderer)
+		}
+		l.moduleRenderers[path] = l.renderer
+	}
+	l.hadError = false
+
+	tokens := scanner.NewScanner(string(source), l.error).InFile(path).ScanTokens()
+	statements, err := parser.NewParser(tokens, l.parseError).Parse()
+	if err != nil {
+		return err
+	}
+	if !l.hadError {
+		if err := compile(statements); err != nil {
+			return err
+		}
+	}
+	if l.hadError {
+		return errors.New("the module has errors")
+	}
+	return nil
+}
+
 func (l *golox) runtimeError(err *interpreter.RuntimeError) {
-	l.reporter.Error("%v\n[line %d]\n", err.Error(), err.Token.Line)
+	if l.renderer != nil {
+		renderer := l.renderer
+		if moduleRenderer, exist := l.moduleRenderers[err.Token.File]; exist {
+			renderer = moduleRenderer
+		}
+		l.reporter.Error("%v", renderer.Render(diagnostic.Diagnostic{
+			Severity: diagnostic.ERROR,
+			Span:     diagnostic.TokenSpan(*err.Token),
+			Message:  err.Error(),
+		}))
+	} else {
+		l.reporter.Error("%v\n[line %d:%d]\n", err.Error(), err.Token.Line, err.Token.Column)
+	}
 	l.hadRuntimeError = true
 }
 
 func (l *golox) parseError(token scanner.Token, message string) {
+	d := diagnostic.Diagnostic{Severity: diagnostic.ERROR, Span: diagnostic.TokenSpan(token), Message: message}
 	if token.TokenType == scanner.EOF {
-		l.report(token.Line, " at end", message)
+		l.report(d, " at end")
 	} else {
-		l.report(token.Line, fmt.Sprintf(" at '%v'", *token.Lexeme), message)
+		l.report(d, fmt.Sprintf(" at '%v'", *token.Lexeme))
 	}
 }
 
-func (l *golox) error(line int, message string) {
-	l.report(line, "", message)
+func (l *golox) error(line int, column int, message string) {
+	l.report(diagnostic.Diagnostic{Severity: diagnostic.ERROR, Span: diagnostic.PointSpan(line, column), Message: message}, "")
 }
 
-func (l *golox) report(line int, where string, message string) {
-	l.reporter.Error("[line %d] Error%v: %v\n", line, where, message)
+// report prints a compile error, where describes the offending token in the one-line format,
+// rendered diagnostics underline it in the source line instead.
+func (l *golox) report(d diagnostic.Diagnostic, where string) {
+	if l.renderer != nil {
+		l.reporter.Error("%v", l.renderer.Render(d))
+	} else {
+		l.reporter.Error("[line %d:%d] Error%v: %v\n", d.Span.Line, d.Span.Column, where, d.Message)
+	}
 	l.hadError = true
 }
 
+// ResetError clears the errors of the previous run, the REPL continues after a failed entry.
 func (l *golox) ResetError() {
 	l.hadError = false
+	l.hadRuntimeError = false
 }
 
-func RunFile(f string) error {
+func RunFile(f string, opts ...Option) error {
 	s, err := os.ReadFile(f)
 	if err != nil {
 		return err
 	}
-	lox := newLox()
-	if err = lox.run(string(s)); err != nil {
+	lox := newLox(f, opts...)
+	if err = lox.run(string(s)); err != nil || lox.hadError {
 		os.Exit(65)
 	}
+	if lox.hadRuntimeError {
+		os.Exit(70)
+	}
 	return nil
 }
 
-func RunPrompt() {
-	lox := newLox()
-	for {
-		fmt.Print("> ")
-		r := bufio.NewReader(os.Stdin)
-		s, err := r.ReadString('\n')
-		if err == io.EOF {
-			return
-		}
-		err = lox.run(s)
-		if err != nil {
-			fmt.Printf("failed to interpret: %v\n", err)
-			lox.ResetError()
-		}
+// ColorEnabled reports whether diagnostics are colored: only when printing to a terminal and NO_COLOR is not set.
+func ColorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
 	}
+	return fi.Mode()&os.ModeCharDevice != 0
 }
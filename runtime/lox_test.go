@@ -1,7 +1,12 @@
 package runtime
 
 import (
+	"flag"
 	"fmt"
+	"github.com/nesyuk/golox/interpreter"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -16,8 +21,9 @@ func TestRun(t *testing.T) {
 		{"print 3.3 + 2.2;", []string{"5.5"}, []string{}, false},
 		{"print 4 * 5;", []string{"20"}, []string{}, false},
 		{"print \"hello,\" + \" world!\";", []string{"hello, world!"}, []string{}, false},
-		{"print (3 + 2;", []string{}, []string{"[line 1] Error at ';': expect ')' after expression.\n"}, false},
-		{"print 3 + \"2\";", []string{}, []string{"Operands must be numbers: 2\n[line 1]\n"}, true},
+		{"print (3 + 2;", []string{}, []string{"[line 1:13] Error at ';': expect ')' after expression.\n"}, false},
+		{"print 3 + \"2\";", []string{"32"}, []string{}, false},
+		{"print 3 + true;", []string{}, []string{"Operands must be numbers: true\n[line 1:9]\n"}, true},
 		{"var a = 1; var b = 2; print a + b;", []string{"3"}, []string{}, false},
 		{"var a = 1; {var a = 2; print a;} print a;", []string{"2", "1"}, []string{}, false},
 		{"var i = 1; while(i < 3) {print i; i = i + 1;}", []string{"1", "2"}, []string{}, false},
@@ -25,24 +31,249 @@ func TestRun(t *testing.T) {
 		{"fun sayHi(first, last) { print \"Hi, \" + first + \" \" + last + \"!\"; }\n sayHi(\"Mr.\", \"Bean\");", []string{"Hi, Mr. Bean!"}, []string{}, false},
 		{"fun fib(n) {\nif (n <= 1) return n;\nreturn fib(n-2) + fib(n-1);\n}\n\nfor (var i = 0; i < 20; i = i + 1) {\nprint fib(i);\n}", []string{"0", "1", "1", "2", "3", "5", "8", "13", "21", "34", "55", "89", "144", "233", "377", "610", "987", "1597", "2584", "4181"}, []string{}, false},
 		{"fun makeCounter() {\nvar i = 0;\nfun count() {\ni = i + 1;\nprint i;\n }\nreturn count;\n}\n\nvar counter = makeCounter();\ncounter(); // 1\ncounter(); // 2", []string{"1", "2"}, []string{}, false},
+		{"var a = 1;\n{\n  var b = b;\n}", []string{}, []string{"[line 3:11] Error at 'b': Can't read local variable in its own initializer.\n"}, false},
+		{"print (1;\nvar = 2;\nclass {}\nprint 3;", []string{}, []string{"[line 1:9] Error at ';': expect ')' after expression.\n", "[line 2:5] Error at '=': expect variable name\n", "[line 3:7] Error at '{': Expect class name\n"}, false},
+		{"print 1;\nprint", []string{}, []string{"[line 2:6] Error at end: expect expression\n"}, false},
+		{"var words = split(\"a,b\", \",\");\nprint len(words);\nprint upper(join(words, \"-\"));\nprint format(\"{}!\", substring(\"hello\", 0, 4));", []string{"2", "A-B", "hell!"}, []string{}, false},
+		{"print 1;\nprint substring(\"hello\", 1, 9);", []string{"1"}, []string{"Substring range [1, 9) is out of bounds for length 5.\n[line 2:30]\n"}, true},
+		{"seed(7);\nvar a = random();\nseed(7);\nprint a == random();\nprint max(1, floor(pow(2, 0.5) * 10), mod(-7, 3));", []string{"true", "14"}, []string{}, false},
+		{"var xs = [1, \"two\", nil];\nxs[2] = xs[0] + 2;\nxs.push([]);\nprint xs;\nprint xs.pop();\nprint xs.len();\nprint len(xs);", []string{"[1, two, 3, []]", "[]", "3", "3"}, []string{}, false},
+		{"var xs = [1];\nprint xs[1];", []string{}, []string{"List index 1 is out of bounds for length 1.\n[line 2:11]\n"}, true},
+		{"var xs = [1];\nxs[0.5] = 1;", []string{}, []string{"List index must be an integer.\n[line 2:7]\n"}, true},
+		{"var xs = \"abc\";\nprint xs[0];", []string{}, []string{"Only lists and maps can be indexed.\n[line 2:11]\n"}, true},
+		{"[].pop();", []string{}, []string{"Can't pop from an empty list.\n[line 1:8]\n"}, true},
+		{"var ages = {\"ann\": 31, \"bob\": 27,};\nages[\"cid\"] = 40;\nages[\"ann\"] = 32;\nprint ages;\nprint ages[\"bob\"];\nprint ages.has(\"bob\");\nprint ages.remove(\"bob\");\nprint ages.has(\"bob\");\nprint ages.keys();\nprint ages.values();\nprint ages.len();", []string{"{ann: 32, bob: 27, cid: 40}", "27", "true", "27", "false", "[ann, cid]", "[32, 40]", "2"}, []string{}, false},
+		{"var m = {1: \"one\", true: \"yes\"};\nm[2 - 1] = \"uno\";\nprint m.entries();\nprint {};", []string{"[[1, uno], [true, yes]]", "{}"}, []string{}, false},
+		{"var m = {\"a\": 1};\nprint m[\"b\"];", []string{}, []string{"Undefined key 'b'.\n[line 2:12]\n"}, true},
+		{"var m = {};\nm[nil] = 1;", []string{}, []string{"Map key must be a string, number or boolean.\n[line 2:6]\n"}, true},
+		{"print {[]: 1};", []string{}, []string{"Map key must be a string, number or boolean.\n[line 1:7]\n"}, true},
+		{"var m = {};\nm[0/0] = 1;", []string{}, []string{"Map key can't be NaN.\n[line 2:6]\n"}, true},
+		{"print {0/0: 1};", []string{}, []string{"Map key can't be NaN.\n[line 1:7]\n"}, true},
+		{"var m = {1: 2};\nprint m.remove(0/0);", []string{}, []string{"Map key can't be NaN.\n[line 2:19]\n"}, true},
+		{"for (var i = 0; i < 10; i = i + 1) {\n  if (i == 1) continue;\n  if (i == 4) break;\n  print i;\n}", []string{"0", "2", "3"}, []string{}, false},
+		{"var i = 0;\nwhile (true) {\n  i = i + 1;\n  var odd = mod(i, 2) == 1;\n  if (odd) continue;\n  if (i > 6) break;\n  print i;\n}\nprint \"done\";", []string{"2", "4", "6", "done"}, []string{}, false},
+		{"var fns = [];\nfor (var i = 0; i < 3; i = i + 1) {\n  var j = i;\n  fun get() { return j; }\n  fns.push(get);\n  if (j == 1) break;\n}\nprint fns[0]() + fns[1]();\nprint fns.len();", []string{"1", "2"}, []string{}, false},
+		{"for (var i = 0; i < 2; i = i + 1) {\n  for (var j = 0; j < 3; j = j + 1) {\n    if (j == 1) break;\n    print j;\n  }\n}", []string{"0", "0"}, []string{}, false},
+		{"break;\nwhile (true) {\n  fun f() { continue; }\n}", []string{}, []string{"[line 1:1] Error at 'break': Can't use 'break' outside of a loop.\n", "[line 3:13] Error at 'continue': Can't use 'continue' outside of a loop.\n"}, false},
+		{"try {\n  print 1;\n  nil();\n  print 2;\n} catch (e) {\n  print e.message;\n  print e.line;\n} finally {\n  print 3;\n}", []string{"1", "Can only call functions and classes.", "3", "3"}, []string{}, false},
+		{"fun f() {\n  try {\n    return 1;\n  } finally {\n    print \"cleanup\";\n  }\n}\nprint f();", []string{"cleanup", "1"}, []string{}, false},
+		{"try {\n  throw \"oops\";\n} finally {\n  print \"finally\";\n}", []string{"finally"}, []string{"Uncaught exception: oops\n[line 2:3]\n"}, true},
+		{"try {\n  try {\n    -\"a\";\n  } catch (e) {\n    throw e;\n  }\n} finally {}", []string{}, []string{"Operand must be a number.\n[line 3:5]\n"}, true},
+		{"class A {\n  init() {\n    throw \"in init\";\n  }\n}\nA();\nprint \"after\";", []string{}, []string{"Uncaught exception: in init\n[line 3:5]\n"}, true},
+		{"try {} print 1;", []string{}, []string{"[line 1:8] Error at 'print': Expect 'catch' or 'finally' after try block.\n"}, false},
+		{"fun apply(f, x) { return f(x); }\nvar offset = 10;\nprint apply(fun (n) { return n + offset; }, 5);\nvar square = fun (n) { return n * n; };\nprint square(4);\nprint square;\nprint fun () {}();", []string{"15", "16", "<anonymous fn.>", "nil"}, []string{}, false},
+		{"fun counter() {\n  var i = 0;\n  return fun () {\n    i = i + 1;\n    return i;\n  };\n}\nvar next = counter();\nnext();\nprint next();", []string{"2"}, []string{}, false},
+		{"fun () { return this; };", []string{}, []string{"[line 1:17] Error at 'this': Can't use 'this' outside of a class.\n"}, false},
+		{"class Math {\n  class square(n) {\n    return n * n;\n  }\n}\nprint Math.square(3);\nprint Math.square;", []string{"9", "<fn 'square'.>"}, []string{}, false},
+		{"class Counter {\n  class next() {\n    Counter.count = Counter.count + 1;\n    return Counter.count;\n  }\n}\nCounter.count = 0;\nCounter.next();\nprint Counter.next();", []string{"2"}, []string{}, false},
+		{"class A {\n  class create() { return A(); }\n}\nclass B < A {}\nprint B.create();\nprint B.missing;", []string{"<'A' instance.>"}, []string{"Undefined property 'missing'.\n[line 6:9]\n"}, true},
+		{"class A {\n  class f() { return this; }\n}\nclass B < A {\n  class g() { return super.f(); }\n}", []string{}, []string{"[line 2:22] Error at 'this': Can't use 'this' in a class method.\n", "[line 5:22] Error at 'super': Can't use 'super' in a class method.\n"}, false},
+		{"class Rect {\n  init(w, h) {\n    this.w = w;\n    this.h = h;\n  }\n  area {\n    return this.w * this.h;\n  }\n}\nvar r = Rect(2, 3);\nprint r.area;\nr.w = 4;\nprint r.area;", []string{"6", "12"}, []string{}, false},
+		{"class A {\n  name { return \"A\"; }\n}\nclass B < A {\n  name { return super.name + \"B\"; }\n}\nprint B().name;", []string{"AB"}, []string{}, false},
+		{"class Config {\n  class version { return 2; }\n}\nprint Config.version;", []string{"2"}, []string{}, false},
+		{"class A {\n  init { }\n}", []string{}, []string{"[line 2:3] Error at 'init': Can't use 'init' as a getter.\n"}, false},
+		{"var name = \"Ada\";\nvar age = 36;\nprint \"Hello ${name}, you are ${age}\";\nprint \"${age + 1}${nil}\";\nprint \"${[1, 2]} ${ {\"a\": true} }\";\nprint \"outer ${\"inner ${name}\"}\";\nprint \"$ and {}\";", []string{"Hello Ada, you are 36", "37nil", "[1, 2] {a: true}", "outer inner Ada", "$ and {}"}, []string{}, false},
+		{"print \"a ${1 2}\";", []string{}, []string{"[line 1:14] Error at '2': Expect '}' after interpolated expression.\n"}, false},
+		{"print 1;\nprint nmae;\nnmae = 2;", []string{}, []string{"[line 2:7] Error at 'nmae': Undefined variable 'nmae'.\n", "[line 3:1] Error at 'nmae': Undefined variable 'nmae'.\n"}, false},
+		{"fun show() { print later; }\nvar later = \"ok\";\nshow();", []string{"ok"}, []string{}, false},
+		{"print later;\nvar later = 1;", []string{}, []string{"Undefined variable 'later'.\n[line 1:7]\n"}, true},
 		{"class Greeting {\n\thello() {\n\t\treturn \"Hello\";\n\t}\n}\n\nprint Greeting;", []string{"<class 'Greeting'.>"}, []string{}, false},
 	}
 
-	for _, test := range tests {
+	for _, backend := range [][]Option{nil, {WithBytecodeVM()}} {
+		for _, test := range tests {
+			reporter := newTestReporter()
+			lox := NewLox(reporter, backend...)
+			err := lox.run(test.expr)
+			if err != nil {
+				t.Error(err)
+			}
+			if len(test.errors) != 0 != lox.hadError && !lox.hadRuntimeError {
+				t.Errorf("expect no error, got '%v'", lox.hadError)
+			}
+			if test.runtimeError != lox.hadRuntimeError {
+				t.Errorf("expect runtime error: %v got %v (in %v)", test.runtimeError, lox.hadRuntimeError, test.expr)
+			}
+			reporter.Validate(t, test.expect, test.errors, test.expr)
+		}
+	}
+}
+
+func TestRunDiagnostics(t *testing.T) {
+	reporter := newTestReporter()
+	lox := NewLox(reporter)
+	lox.diagnostics = &diagnosticOptions{"test.lox", false}
+	if err := lox.run("print 1;\nprint \"a\" - 1;"); err != nil {
+		t.Error(err)
+	}
+	reporter.Validate(t, []string{"1"}, []string{"error: Operands must be a numbers.\n --> test.lox:2:11\n  |\n2 | print \"a\" - 1;\n  |           ^\n"}, "diagnostics")
+
+	reporter = newTestReporter()
+	lox = NewLox(reporter)
+	lox.diagnostics = &diagnosticOptions{"test.lox", false}
+	if err := lox.run("print (1;\nprint"); err != nil {
+		t.Error(err)
+	}
+	reporter.Validate(t, []string{}, []string{
+		"error: expect ')' after expression.\n --> test.lox:1:9\n  |\n1 | print (1;\n  |         ^\n",
+		"error: expect expression\n --> test.lox:2:6\n  |\n2 | print\n  |      ^\n",
+	}, "parse diagnostics")
+}
+
+func TestRunImport(t *testing.T) {
+	dir := t.TempDir()
+	for name, source := range map[string]string{
+		"lib/geometry.lox": "print \"loading\";\nvar count = 0;\nfun area(w, h) {\n  count = count + 1;\n  return scale(w) * h;\n}\nfun scale(x) { return x; }",
+		"a.lox":            "import \"b.lox\";",
+		"b.lox":            "import \"a.lox\";",
+		"broken.lox":       "var = 1;",
+		"lib/dice.lox":     "fun roll() { return random(); }",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		expr         string
+		expect       []string
+		errors       []string
+		runtimeError bool
+	}{
+		{"import \"lib/geometry.lox\";\nimport area from \"lib/geometry.lox\";\nprint geometry;\nprint geometry.area(2, 3);\nprint area(4, 5);\nprint geometry.count;", []string{"loading", "<module 'geometry'.>", "6", "20", "2"}, []string{}, false},
+		{"import \"lib/geometry.lox\";\nprint geometry.clock;", []string{"loading"}, []string{"Module 'geometry' has no member 'clock'.\n[line 2:16]\n"}, true},
+		{"import missing from \"lib/geometry.lox\";", []string{"loading"}, []string{"Module 'geometry' has no member 'missing'.\n[line 1:8]\n"}, true},
+		{"import \"a.lox\";", []string{}, []string{"Import cycle: a.lox -> b.lox -> a.lox.\n[line 1:8]\n"}, true},
+		{"import \"main.lox\";", []string{}, []string{"Import cycle: main.lox -> main.lox.\n[line 1:8]\n"}, true},
+		{"import \"missing.lox\";", []string{}, []string{"Can't import 'missing.lox': no such file.\n[line 1:8]\n"}, true},
+		{"import \"broken.lox\";", []string{}, []string{"[line 1:5] Error at '=': expect variable name\n", "Can't import 'broken.lox': the module has errors.\n[line 1:8]\n"}, true},
+		{"import roll from \"lib/dice.lox\";\nseed(3);\nvar a = roll();\nseed(3);\nprint a == random();", []string{"true"}, []string{}, false},
+		{"import \"my-lib.lox\";", []string{}, []string{"[line 1:8] Error at '\"my-lib.lox\"': Module name 'my-lib' is not a valid identifier.\n"}, false},
+	}
+	for _, backend := range [][]Option{nil, {WithBytecodeVM()}} {
+		for _, test := range tests {
+			reporter := newTestReporter()
+			lox := NewLox(reporter, backend...)
+			lox.filename = filepath.Join(dir, "main.lox")
+			if err := lox.run(test.expr); err != nil {
+				t.Error(err)
+			}
+			if test.runtimeError != lox.hadRuntimeError {
+				t.Errorf("expect runtime error: %v got %v (in %v)", test.runtimeError, lox.hadRuntimeError, test.expr)
+			}
+			reporter.Validate(t, test.expect, test.errors, test.expr)
+		}
+	}
+}
+
+func TestRunImportDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.lox"), []byte("fun half(x) {\n  return x / 2;\n}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range [][]Option{nil, {WithBytecodeVM()}} {
 		reporter := newTestReporter()
-		lox := NewLox(reporter)
-		err := lox.run(test.expr)
+		lox := NewLox(reporter, backend...)
+		lox.filename = filepath.Join(dir, "main.lox")
+		lox.diagnostics = &diagnosticOptions{"main.lox", false}
+		if err := lox.run("import half from \"lib.lox\";\nprint half(\"a\");"); err != nil {
+			t.Error(err)
+		}
+		path := filepath.Join(dir, "lib.lox")
+		reporter.Validate(t, []string{}, []string{"error: Operands must be a numbers.\n --> " + path + ":2:12\n  |\n2 |   return x / 2;\n  |            ^\n"}, "module diagnostics")
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files of TestRunGolden")
+
+// TestRunGolden runs every testdata/*.lox program and compares its output to the .golden file next to it.
+func TestRunGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.lox")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("expect golden programs, got %v (%v)", files, err)
+	}
+	for _, f := range files {
+		source, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := strings.TrimSuffix(f, ".lox") + ".golden"
+		for _, backend := range [][]Option{nil, {WithBytecodeVM()}} {
+			reporter := newTestReporter()
+			if err := NewLox(reporter, backend...).run(string(source)); err != nil {
+				t.Error(err)
+			}
+			got := strings.Join(append(reporter.got, reporter.errors...), "\n") + "\n"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expect, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(expect) {
+				t.Errorf("%v: output differs from %v:\n%v", f, golden, got)
+			}
+		}
+	}
+}
+
+func TestRunBackendsAgree(t *testing.T) {
+	files, err := filepath.Glob("../files/*.lox")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("expect example files, got %v (%v)", files, err)
+	}
+	for _, f := range files {
+		source, err := os.ReadFile(f)
 		if err != nil {
+			t.Fatal(err)
+		}
+		treeWalker := newTestReporter()
+		if err := NewLox(treeWalker).run(string(source)); err != nil {
 			t.Error(err)
 		}
-		if len(test.errors) != 0 != lox.hadError && !lox.hadRuntimeError {
-			t.Errorf("expect no error, got '%v'", lox.hadError)
+		bytecode := newTestReporter()
+		if err := NewLox(bytecode, WithBytecodeVM()).run(string(source)); err != nil {
+			t.Error(err)
+		}
+		bytecode.Validate(t, treeWalker.got, treeWalker.errors, f)
+	}
+}
+
+// Natives registered on either backend accept the same parameters and give the same results.
+func TestRegisterFuncBackendsAgree(t *testing.T) {
+	source := "print twice(2);\nprint same(twice);\nprint twice(\"a\");"
+	treeWalker, bytecode := newTestReporter(), newTestReporter()
+	for _, backend := range []struct {
+		reporter *testReporter
+		register func(l *golox) func(name string, fn interface{}) error
+		opts     []Option
+	}{
+		{treeWalker, func(l *golox) func(string, interface{}) error { return l.interpreter().RegisterFunc }, nil},
+		{bytecode, func(l *golox) func(string, interface{}) error { return l.vm().RegisterFunc }, []Option{WithBytecodeVM()}},
+	} {
+		lox := NewLox(backend.reporter, backend.opts...)
+		register := backend.register(lox)
+		if err := register("twice", func(n float64) float64 { return n * 2 }); err != nil {
+			t.Fatal(err)
+		}
+		if err := register("same", func(value interface{}) interface{} { return value }); err != nil {
+			t.Fatal(err)
+		}
+		if err := register("call", func(fn interpreter.LoxCallable) {}); err == nil {
+			t.Errorf("expect callable parameters to be rejected")
 		}
-		if test.runtimeError != lox.hadRuntimeError {
-			t.Errorf("expect runtime error: %v got %v (in %v)", test.runtimeError, lox.hadRuntimeError, test.expr)
+		if err := lox.run(source); err != nil {
+			t.Error(err)
 		}
-		reporter.Validate(t, test.expect, test.errors, test.expr)
 	}
+	treeWalker.Validate(t, []string{"4", "<native fn 'twice'>"}, []string{"Argument 1 of 'twice' must be a number.\n[line 3:16]\n"}, "tree-walker")
+	bytecode.Validate(t, treeWalker.got, treeWalker.errors, "bytecode")
 }
 
 type testReporter struct {
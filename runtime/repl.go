@@ -0,0 +1,270 @@
+package runtime
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/nesyuk/golox/resolver"
+	"github.com/nesyuk/golox/scanner"
+	"github.com/nesyuk/golox/token"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	prompt             = "> "
+	continuationPrompt = "... "
+	historyFile        = ".golox_history"
+	historySize        = 1000
+)
+
+// lineReader reads the lines of REPL entries.
+type lineReader interface {
+	ReadLine() (string, error)
+	SetPrompt(prompt string)
+}
+
+func RunPrompt(opts ...Option) {
+	lox := newLox("<stdin>", opts...)
+	lox.echo = true
+	lox.undefinedCheck = resolver.CHECK_TOP_LEVEL
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		lox.prompt(&plainReader{bufio.NewReader(os.Stdin), os.Stdout, prompt})
+		return
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		lox.prompt(&plainReader{bufio.NewReader(os.Stdin), os.Stdout, prompt})
+		return
+	}
+	defer term.Restore(fd, state)
+
+	// x/term keeps the history of a terminal to itself, the saved entries are typed in
+	// before the input, with the output discarded, so the arrow keys recall them.
+	var h *history
+	conn := &terminalConn{strings.NewReader(""), io.Discard}
+	if home, err := os.UserHomeDir(); err == nil {
+		h = loadHistory(filepath.Join(home, historyFile), historySize)
+		conn.in = strings.NewReader(h.replay())
+	}
+	t := term.NewTerminal(conn, prompt)
+	for idx := 0; h != nil && idx < h.Len(); idx++ {
+		t.ReadLine()
+	}
+	conn.in, conn.out = os.Stdin, os.Stdout
+	// The terminal translates line endings while it is in raw mode.
+	lox.reporter = &writerReporter{t}
+	lox.prompt(&terminalReader{t, h})
+}
+
+type terminalConn struct {
+	in  io.Reader
+	out io.Writer
+}
+
+func (c *terminalConn) Read(p []byte) (int, error) {
+	return c.in.Read(p)
+}
+
+func (c *terminalConn) Write(p []byte) (int, error) {
+	return c.out.Write(p)
+}
+
+// prompt runs entries until the input ends, an entry continues over several lines
+// while its braces, brackets, parentheses or strings are left open.
+func (l *golox) prompt(r lineReader) {
+	var entry strings.Builder
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		entry.WriteString(line)
+		entry.WriteString("\n")
+		if incomplete(entry.String()) {
+			r.SetPrompt(continuationPrompt)
+			continue
+		}
+		r.SetPrompt(prompt)
+		source := entry.String()
+		entry.Reset()
+		if err := l.run(source); err != nil {
+			l.reporter.Error("failed to interpret: %v\n", err)
+		}
+		l.ResetError()
+	}
+}
+
+// incomplete reports whether the source ends inside a string, a comment or an open brace, bracket or parenthesis.
+func incomplete(source string) bool {
+	unterminated := false
+	tokens := scanner.NewScanner(source, func(line int, column int, message string) {
+		if message == "Unterminated string." || message == "Unterminated comment." {
+			unterminated = true
+		}
+	}).ScanTokens()
+	if unterminated {
+		return true
+	}
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.TokenType {
+		case scanner.LEFT_PAREN, scanner.LEFT_BRACE, scanner.LEFT_BRACKET:
+			depth++
+		case scanner.RIGHT_PAREN, scanner.RIGHT_BRACE, scanner.RIGHT_BRACKET:
+			depth--
+		case scanner.INTERPOLATION:
+			// A segment starting with '}' closes one interpolation and opens the next.
+			if strings.HasPrefix(*tok.Lexeme, "\"") {
+				depth++
+			}
+		case scanner.STRING:
+			if strings.HasPrefix(*tok.Lexeme, "}") {
+				depth--
+			}
+		}
+	}
+	return depth > 0
+}
+
+// echoLast prints the value of a trailing expression statement, assignments are not echoed
+// and neither are calls returning nil.
+func echoLast(statements []token.Stmt) []token.Stmt {
+	if len(statements) == 0 {
+		return statements
+	}
+	last, ok := statements[len(statements)-1].(*token.ExpressionStmt)
+	if !ok {
+		return statements
+	}
+	switch last.Expression.(type) {
+	case *token.AssignExpr, *token.SetExpr, *token.IndexSetExpr:
+		return statements
+	case *token.CallExpr:
+		statements[len(statements)-1] = echoCall(last.Expression.(*token.CallExpr))
+		return statements
+	}
+	statements[len(statements)-1] = &token.PrintStmt{Expression: last.Expression}
+	return statements
+}
+
+// echoCall prints the result of a call unless it is nil:
+//
+//	{ var result = call; if (result != nil) print result; }
+//
+// The variable is named so it can't be used by the call.
+func echoCall(call *token.CallExpr) token.Stmt {
+	name, operator := *call.Paren, *call.Paren
+	lexeme := "echo result"
+	name.TokenType, name.Lexeme = scanner.IDENTIFIER, &lexeme
+	operator.TokenType = scanner.BANG_EQUAL
+	return &token.BlockStmt{Statements: []token.Stmt{
+		&token.VarStmt{Name: name, Initializer: call},
+		&token.IfStmt{
+			Condition:  &token.BinaryExpr{Left: &token.VariableExpr{Name: name}, Operator: operator, Right: &token.LiteralExpr{Value: nil}},
+			ThenBranch: &token.PrintStmt{Expression: &token.VariableExpr{Name: name}},
+		},
+	}}
+}
+
+type terminalReader struct {
+	t *term.Terminal
+	// Saves the lines, nil when there is no home directory.
+	history *history
+}
+
+func (r *terminalReader) ReadLine() (string, error) {
+	line, err := r.t.ReadLine()
+	// Pasted lines are run like typed ones.
+	if errors.Is(err, term.ErrPasteIndicator) {
+		err = nil
+	}
+	if err == nil && r.history != nil {
+		r.history.Add(line)
+	}
+	return line, err
+}
+
+func (r *terminalReader) SetPrompt(prompt string) {
+	r.t.SetPrompt(prompt)
+}
+
+// plainReader reads lines when the input is not a terminal, e.g. a pipe.
+type plainReader struct {
+	in     *bufio.Reader
+	out    io.Writer
+	prompt string
+}
+
+func (r *plainReader) ReadLine() (string, error) {
+	fmt.Fprint(r.out, r.prompt)
+	line, err := r.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+func (r *plainReader) SetPrompt(prompt string) {
+	r.prompt = prompt
+}
+
+// history keeps the most recent entries in memory and appends every entry to a file,
+// the file is truncated to the most recent entries when it is loaded.
+type history struct {
+	path    string
+	size    int
+	entries []string
+}
+
+func loadHistory(path string, size int) *history {
+	h := &history{path: path, size: size}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > size {
+		h.entries = h.entries[len(h.entries)-size:]
+		os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600)
+	}
+	return h
+}
+
+func (h *history) Add(entry string) {
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[1:]
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, entry)
+}
+
+func (h *history) Len() int {
+	return len(h.entries)
+}
+
+// replay returns the entries as typed on a terminal, the oldest first.
+func (h *history) replay() string {
+	var b strings.Builder
+	for _, entry := range h.entries {
+		b.WriteString(entry)
+		b.WriteString("\r")
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package runtime
+
+import (
+	"github.com/nesyuk/golox/resolver"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		source string
+		expect bool
+	}{
+		{"print 1;", false},
+		{"fun f() {", true},
+		{"fun f() {\n  return 1;\n}", false},
+		{"print (1 +", true},
+		{"var l = [1,", true},
+		{"print \"abc", true},
+		{"print \"a\nb\";", false},
+		{"print \"a ${1 +", true},
+		{"print \"a ${1} b ${", true},
+		{"print \"a ${ {} }\";", false},
+		{"}", false},
+		{"// {", false},
+		{"/* {", true},
+		{"/* a /* b */", true},
+		{"/* a /* b */ */ print 1;", false},
+	}
+	for _, test := range tests {
+		if got := incomplete(test.source); got != test.expect {
+			t.Errorf("expect %v, got: %v (in %q)", test.expect, got, test.source)
+		}
+	}
+}
+
+type testLineReader struct {
+	lines   []string
+	prompts []string
+}
+
+func (r *testLineReader) ReadLine() (string, error) {
+	if len(r.lines) == 0 {
+		return "", io.EOF
+	}
+	line := r.lines[0]
+	r.lines = r.lines[1:]
+	return line, nil
+}
+
+func (r *testLineReader) SetPrompt(prompt string) {
+	r.prompts = append(r.prompts, prompt)
+}
+
+func TestPrompt(t *testing.T) {
+	for _, backend := range [][]Option{{}, {WithBytecodeVM()}} {
+		reporter := newTestReporter()
+		lox := NewLox(reporter, backend...)
+		lox.echo = true
+		lox.undefinedCheck = resolver.CHECK_TOP_LEVEL
+		r := &testLineReader{lines: []string{
+			"{",
+			"  var a = 1;",
+			"  print a;",
+			"}",
+			"1 + 2;",
+			"var x = 1;",
+			"\"a ${x +",
+			"1} b\";",
+			"var y; y = 3;",
+			"fun f() { return x + y; }",
+			"-\"a\";",
+			"print ;",
+			"print z;",
+			"f();",
+			"fun isEven(n) { if (n == 0) return true; return isOdd(n - 1); }",
+			"fun isOdd(n) { if (n == 0) return false; return isEven(n - 1); }",
+			"isEven(4);",
+			"fun g() { return w; }",
+			"g();",
+			"fun h() {}",
+			"h();",
+			"len(\"ab\");",
+		}}
+		lox.prompt(r)
+		reporter.Validate(t, []string{"1", "3", "a 2 b", "4", "true", "2"}, []string{
+			"Operand must be a number.\n[line 1:1]\n",
+			"[line 1:7] Error at ';': expect expression\n",
+			"[line 1:7] Error at 'z': Undefined variable 'z'.\n",
+			"Undefined variable 'w'.\n[line 1:18]\n",
+		}, "prompt")
+		expect := []string{"... ", "... ", "... ", "> ", "> ", "> ", "... ", "> ", "> ", "> ", "> ", "> ", "> ", "> ", "> ", "> ", "> ", "> ", "> ", "> ", "> ", "> "}
+		if strings.Join(r.prompts, "|") != strings.Join(expect, "|") {
+			t.Errorf("expect prompts %q, got: %q", expect, r.prompts)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), historyFile)
+	if err := os.WriteFile(path, []byte("print 1;\nprint 2;\nprint 3;\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h := loadHistory(path, 2)
+	if h.Len() != 2 || h.replay() != "print 2;\rprint 3;\r" {
+		t.Errorf("expect the 2 most recent entries, got: %v", h.entries)
+	}
+	h.Add("print 4;")
+	h.Add("  ")
+	if h.Len() != 2 || h.replay() != "print 3;\rprint 4;\r" {
+		t.Errorf("expect 'print 4;' to be the most recent entry, got: %v", h.entries)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "print 2;\nprint 3;\nprint 4;\n" {
+		t.Errorf("expect the trimmed history with the new entry, got: %q", data)
+	}
+	if h := loadHistory(path, 10); h.Len() != 3 {
+		t.Errorf("expect 3 entries after reloading, got: %v", h.entries)
+	}
+
+	// The replayed entries are recalled with the up arrow.
+	conn := &terminalConn{strings.NewReader(h.replay() + "\x1b[A\x1b[A\r"), io.Discard}
+	terminal := term.NewTerminal(conn, prompt)
+	for idx := 0; idx < h.Len(); idx++ {
+		terminal.ReadLine()
+	}
+	if line, err := terminal.ReadLine(); err != nil || line != "print 3;" {
+		t.Errorf("expect 'print 3;' to be recalled, got: %q, %v", line, err)
+	}
+}
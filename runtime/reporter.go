@@ -1,6 +1,9 @@
 package runtime
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
 
 type Reporter interface {
 	Error(format string, a ...any)
@@ -15,5 +18,18 @@ func (r *StdoutReporter) Error(format string, a ...any) {
 }
 
 func (r *StdoutReporter) Print(s string) {
-	fmt.Print(s)
+	fmt.Println(s)
+}
+
+// writerReporter writes errors and printed values to the given writer.
+type writerReporter struct {
+	w io.Writer
+}
+
+func (r *writerReporter) Error(format string, a ...any) {
+	fmt.Fprintf(r.w, format, a...)
+}
+
+func (r *writerReporter) Print(s string) {
+	fmt.Fprintln(r.w, s)
 }
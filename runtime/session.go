@@ -0,0 +1,144 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nesyuk/golox/diagnostic"
+	"github.com/nesyuk/golox/interpreter"
+	"github.com/nesyuk/golox/parser"
+	"github.com/nesyuk/golox/resolver"
+	"github.com/nesyuk/golox/scanner"
+	"io"
+	"strings"
+)
+
+type ErrorKind uint8
+
+const (
+	// COMPILE_ERROR is a syntax or a static (resolver) error, nothing was executed.
+	COMPILE_ERROR ErrorKind = iota
+	RUNTIME_ERROR
+)
+
+// Error is returned by Session, it holds every problem found in the evaluated source.
+type Error struct {
+	Kind        ErrorKind
+	Diagnostics []diagnostic.Diagnostic
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		if d.Span.Line == 0 {
+			messages = append(messages, d.Message)
+		} else {
+			messages = append(messages, fmt.Sprintf("[line %d:%d] %v", d.Span.Line, d.Span.Column, d.Message))
+		}
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Session embeds Lox into a Go program. Global definitions survive between evaluations,
+// errors are returned to the caller and output of print statements goes to the given writer.
+// A Session is not safe for concurrent use.
+type Session struct {
+	interpreter *interpreter.Interpreter
+	out         io.Writer
+	diagnostics []diagnostic.Diagnostic
+}
+
+func NewSession(out io.Writer) *Session {
+	s := &Session{out: out}
+	s.interpreter = interpreter.New(s.runtimeError, s.print)
+	return s
+}
+
+// Eval runs the source and returns the value of its last statement when it is an expression.
+func (s *Session) Eval(source string) (interface{}, error) {
+	s.diagnostics = nil
+	tokens := scanner.NewScanner(source, s.scanError).ScanTokens()
+	statements, err := parser.NewParser(tokens, s.compileError).Parse()
+	if err != nil {
+		return nil, err
+	}
+	if len(s.diagnostics) == 0 {
+		// Like the REPL, a function may use a global defined by a later evaluation.
+		res := resolver.New(s.interpreter, s.compileError).CheckUndefined(resolver.CHECK_TOP_LEVEL)
+		res.DeclareGlobals(s.interpreter.GlobalNames()...)
+		res.Resolve(statements)
+	}
+	if len(s.diagnostics) != 0 {
+		return nil, &Error{COMPILE_ERROR, s.diagnostics}
+	}
+
+	value, err := s.interpreter.Evaluate(statements)
+	if err != nil {
+		return nil, s.wrapRuntimeError(err)
+	}
+	return value, nil
+}
+
+// Define binds a global variable visible to every following evaluation.
+// Go integers are stored as Lox numbers.
+func (s *Session) Define(name string, value interface{}) {
+	s.interpreter.Define(name, interpreter.ToLox(value))
+}
+
+// DefineFunc binds a Go function as a global Lox function, see interpreter.Interpreter.RegisterFunc.
+func (s *Session) DefineFunc(name string, fn interface{}) error {
+	return s.interpreter.RegisterFunc(name, fn)
+}
+
+// Get returns the value of a global variable.
+func (s *Session) Get(name string) (interface{}, bool) {
+	return s.interpreter.Global(name)
+}
+
+// Call invokes the global function or class with the given name.
+func (s *Session) Call(name string, args ...interface{}) (interface{}, error) {
+	value, exist := s.interpreter.Global(name)
+	if !exist {
+		return nil, &Error{RUNTIME_ERROR, []diagnostic.Diagnostic{{Message: fmt.Sprintf("Undefined variable '%v'.", name)}}}
+	}
+	callee, ok := value.(interpreter.LoxCallable)
+	if !ok {
+		return nil, &Error{RUNTIME_ERROR, []diagnostic.Diagnostic{{Message: fmt.Sprintf("'%v' is not callable.", name)}}}
+	}
+	arguments := make([]interface{}, len(args))
+	for i := range args {
+		arguments[i] = interpreter.ToLox(args[i])
+	}
+	result, err := s.interpreter.Call(callee, arguments)
+	if err != nil {
+		return nil, s.wrapRuntimeError(err)
+	}
+	return result, nil
+}
+
+func (s *Session) wrapRuntimeError(err error) error {
+	var runtimeErr *interpreter.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		return err
+	}
+	d := diagnostic.Diagnostic{Severity: diagnostic.ERROR, Message: runtimeErr.Message}
+	if runtimeErr.Token != nil {
+		d.Span = diagnostic.TokenSpan(*runtimeErr.Token)
+	}
+	return &Error{RUNTIME_ERROR, []diagnostic.Diagnostic{d}}
+}
+
+func (s *Session) scanError(line int, column int, message string) {
+	s.diagnostics = append(s.diagnostics, diagnostic.Diagnostic{Severity: diagnostic.ERROR, Span: diagnostic.PointSpan(line, column), Message: message})
+}
+
+func (s *Session) compileError(token scanner.Token, message string) {
+	s.diagnostics = append(s.diagnostics, diagnostic.Diagnostic{Severity: diagnostic.ERROR, Span: diagnostic.TokenSpan(token), Message: message})
+}
+
+// Runtime errors are returned by Evaluate, the callback is only used by Interpret.
+func (s *Session) runtimeError(_ *interpreter.RuntimeError) {
+}
+
+func (s *Session) print(str string) {
+	fmt.Fprintln(s.out, str)
+}
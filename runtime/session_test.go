@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSessionEval(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := NewSession(out)
+	if _, err := s.Eval("var greeting = \"Hi\";\nfun greet(name) { return greeting + \", \" + name; }"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Eval("print greet(\"Bob\");\ngreet(\"Alice\");")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Hi, Alice" {
+		t.Errorf("expect 'Hi, Alice', got: '%v'", got)
+	}
+	if out.String() != "Hi, Bob\n" {
+		t.Errorf("expect 'Hi, Bob\\n', got: '%v'", out.String())
+	}
+	if got, _ := s.Eval("var x = 1;"); got != nil {
+		t.Errorf("expect nil for a declaration, got: '%v'", got)
+	}
+
+	// A function may use a global defined by a later evaluation.
+	if _, err := s.Eval("fun isEven(n) { if (n == 0) return true; return isOdd(n - 1); }"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Eval("fun isOdd(n) { if (n == 0) return false; return isEven(n - 1); }"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Eval("isEven(3);"); err != nil || got != false {
+		t.Errorf("expect false, got: '%v', %v", got, err)
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		kind   ErrorKind
+		expect string
+	}{
+		{"print (1;\nvar = 2;", COMPILE_ERROR, "[line 1:9] expect ')' after expression.\n[line 2:5] expect variable name"},
+		{"return 1;", COMPILE_ERROR, "[line 1:1] Can't return from top-level code."},
+		{"print \"a\";\n@", COMPILE_ERROR, "[line 2:1] Unexpected character."},
+		{"print 1;\n-\"a\";", RUNTIME_ERROR, "[line 2:1] Operand must be a number."},
+	}
+	for _, test := range tests {
+		_, err := NewSession(&bytes.Buffer{}).Eval(test.source)
+		var loxErr *Error
+		if !errors.As(err, &loxErr) {
+			t.Fatalf("expect *Error, got: %v (in %v)", err, test.source)
+		}
+		if loxErr.Kind != test.kind {
+			t.Errorf("expect kind %v, got: %v (in %v)", test.kind, loxErr.Kind, test.source)
+		}
+		if loxErr.Error() != test.expect {
+			t.Errorf("expect: '%v', got: '%v'", test.expect, loxErr.Error())
+		}
+	}
+}
+
+func TestSessionDefineAndCall(t *testing.T) {
+	s := NewSession(&bytes.Buffer{})
+	s.Define("base", 10)
+	if _, err := s.Eval("fun add(a, b) { return base + a + b; }\nclass Point { init(x) { this.x = x; } }"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Call("add", 1, 2.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 13.5 {
+		t.Errorf("expect 13.5, got: %v", got)
+	}
+	if _, err = s.Call("Point", 1); err != nil {
+		t.Fatal(err)
+	}
+	if value, exist := s.Get("base"); !exist || value != 10.0 {
+		t.Errorf("expect 10, got: %v", value)
+	}
+
+	for _, test := range []struct {
+		name   string
+		args   []interface{}
+		expect string
+	}{
+		{"add", []interface{}{1}, "Expected 2 arguments but got 1."},
+		{"missing", nil, "Undefined variable 'missing'."},
+		{"base", nil, "'base' is not callable."},
+	} {
+		_, err := s.Call(test.name, test.args...)
+		if err == nil || err.Error() != test.expect {
+			t.Errorf("expect: '%v', got: '%v'", test.expect, err)
+		}
+	}
+}
+
+func TestSessionDefineFunc(t *testing.T) {
+	s := NewSession(&bytes.Buffer{})
+	if err := s.DefineFunc("parseFlag", func(value string) (bool, error) {
+		switch value {
+		case "on":
+			return true, nil
+		case "off":
+			return false, nil
+		}
+		return false, errors.New("Unknown flag.")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Eval("parseFlag(\"on\");"); err != nil || got != true {
+		t.Errorf("expect true, got: %v (%v)", got, err)
+	}
+	_, err := s.Eval("parseFlag(\"maybe\");")
+	if err == nil || err.Error() != "[line 1:18] Unknown flag." {
+		t.Errorf("expect '[line 1:18] Unknown flag.', got: %v", err)
+	}
+	if err := s.DefineFunc("bad", 1); err == nil {
+		t.Errorf("expect error for a non function")
+	}
+}
@@ -5,22 +5,29 @@ import (
 )
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":      AND,
+	"break":    BREAK,
+	"catch":    CATCH,
+	"class":    CLASS,
+	"continue": CONTINUE,
+	"else":     ELSE,
+	"false":    FALSE,
+	"finally":  FINALLY,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"import":   IMPORT,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"throw":    THROW,
+	"true":     TRUE,
+	"try":      TRY,
+	"var":      VAR,
+	"while":    WHILE,
 }
 
 type Scanner struct {
@@ -28,6 +35,14 @@ type Scanner struct {
 	tokens         []Token
 	start, current int
 	line           int
+	// Byte offset of the first character of the current line.
+	lineStart int
+	// Position of the token being scanned.
+	startLine, startColumn int
+	// Unclosed braces of every string interpolation being scanned, innermost last.
+	interpolations []int
+	keepComments   bool
+	file           string
 	errorCallback  ErrorCallback
 }
 
@@ -35,13 +50,38 @@ func NewScanner(source string, onError ErrorCallback) *Scanner {
 	return &Scanner{source: source, tokens: make([]Token, 0), start: 0, current: 0, line: 1, errorCallback: onError}
 }
 
+// KeepComments makes the scanner emit a COMMENT token for every comment, for tools that rewrite the source.
+// The parser doesn't accept COMMENT tokens.
+func (sc *Scanner) KeepComments() *Scanner {
+	sc.keepComments = true
+	return sc
+}
+
+// InFile records the path of an imported file on the tokens, errors at runtime are shown in that file.
+func (sc *Scanner) InFile(path string) *Scanner {
+	sc.file = path
+	return sc
+}
+
 func (sc *Scanner) ScanTokens() []Token {
 	sc.start = sc.current
 	for !sc.isAtEnd() {
 		sc.start = sc.current
+		sc.startLine, sc.startColumn = sc.line, sc.column()
 		sc.scanToken()
 	}
-	sc.tokens = append(sc.tokens, Token{TokenType: EOF})
+	sc.start = sc.current
+	column := sc.column()
+	sc.tokens = append(sc.tokens, Token{
+		TokenType: EOF,
+		Line:      sc.line,
+		Column:    column,
+		EndLine:   sc.line,
+		EndColumn: column,
+		Offset:    sc.current,
+		EndOffset: sc.current,
+		File:      sc.file,
+	})
 	return sc.tokens
 }
 
@@ -53,11 +93,27 @@ func (sc *Scanner) scanToken() {
 	case char == ')':
 		sc.addToken(RIGHT_PAREN)
 	case char == '{':
+		if len(sc.interpolations) > 0 {
+			sc.interpolations[len(sc.interpolations)-1]++
+		}
 		sc.addToken(LEFT_BRACE)
+	case char == '}' && len(sc.interpolations) > 0 && sc.interpolations[len(sc.interpolations)-1] == 0:
+		// The end of an interpolated expression, the string continues.
+		sc.interpolations = sc.interpolations[:len(sc.interpolations)-1]
+		sc.addStringToken()
 	case char == '}':
+		if len(sc.interpolations) > 0 {
+			sc.interpolations[len(sc.interpolations)-1]--
+		}
 		sc.addToken(RIGHT_BRACE)
+	case char == '[':
+		sc.addToken(LEFT_BRACKET)
+	case char == ']':
+		sc.addToken(RIGHT_BRACKET)
 	case char == ',':
 		sc.addToken(COMMA)
+	case char == ':':
+		sc.addToken(COLON)
 	case char == '.':
 		sc.addToken(DOT)
 	case char == '-':
@@ -89,12 +145,17 @@ func (sc *Scanner) scanToken() {
 		for sc.peek() != '\n' && !sc.isAtEnd() {
 			sc.advance()
 		}
+		if sc.keepComments {
+			sc.addTokenLiteral(COMMENT, sc.source[sc.start+2:sc.current])
+		}
+	case char == '/' && sc.match('*'):
+		sc.blockComment()
 	case char == '/':
 		sc.addToken(SLASH)
 	case char == ' ' || char == '\r' || char == '\t':
 		// Ignore whitespace
 	case char == '\n':
-		sc.line++
+		sc.newLine()
 	case char == '"':
 		sc.addStringToken()
 	case isDigit(char):
@@ -102,7 +163,7 @@ func (sc *Scanner) scanToken() {
 	case isAlpha(char):
 		sc.addIdentifier()
 	default:
-		sc.errorCallback(sc.line, "Unexpected character.")
+		sc.errorCallback(sc.startLine, sc.startColumn, "Unexpected character.")
 	}
 }
 
@@ -112,7 +173,18 @@ func (sc *Scanner) addToken(tokenType TokenType) {
 
 func (sc *Scanner) addTokenLiteral(tokenType TokenType, literal interface{}) {
 	lexeme := sc.source[sc.start:sc.current]
-	sc.tokens = append(sc.tokens, Token{TokenType: tokenType, Lexeme: &lexeme, Literal: literal, Line: sc.line})
+	sc.tokens = append(sc.tokens, Token{
+		TokenType: tokenType,
+		Lexeme:    &lexeme,
+		Literal:   literal,
+		Line:      sc.startLine,
+		Column:    sc.startColumn,
+		EndLine:   sc.line,
+		EndColumn: sc.column(),
+		Offset:    sc.start,
+		EndOffset: sc.current,
+		File:      sc.file,
+	})
 }
 
 func (sc *Scanner) addIdentifier() {
@@ -142,21 +214,28 @@ func (sc *Scanner) addNumberToken() {
 	}
 	value, err := strconv.ParseFloat(sc.source[sc.start:sc.current], 64)
 	if err != nil {
-		sc.errorCallback(sc.line, "Unexpected character.")
+		sc.errorCallback(sc.startLine, sc.startColumn, "Unexpected character.")
 		return
 	}
 	sc.addTokenLiteral(NUMBER, value)
 }
 
+// addStringToken scans the rest of a string started by '"' or by the '}' closing an interpolation.
 func (sc *Scanner) addStringToken() {
 	for sc.peek() != '"' && !sc.isAtEnd() {
-		if sc.peek() == '\n' {
-			sc.line++
+		if sc.peek() == '$' && sc.peekNext() == '{' {
+			sc.advance()
+			sc.advance()
+			sc.interpolations = append(sc.interpolations, 0)
+			sc.addTokenLiteral(INTERPOLATION, sc.source[sc.start+1:sc.current-2])
+			return
+		}
+		if sc.advance() == '\n' {
+			sc.newLine()
 		}
-		sc.advance()
 	}
 	if sc.isAtEnd() {
-		sc.errorCallback(sc.line, "Unterminated string.")
+		sc.errorCallback(sc.startLine, sc.startColumn, "Unterminated string.")
 		return
 	}
 	// The closing " .
@@ -166,6 +245,28 @@ func (sc *Scanner) addStringToken() {
 	sc.addTokenLiteral(STRING, value)
 }
 
+// blockComment skips a comment started by '/*', comments nest so every '/*' needs its own '*/'.
+func (sc *Scanner) blockComment() {
+	for depth := 1; depth > 0; {
+		switch {
+		case sc.isAtEnd():
+			sc.errorCallback(sc.startLine, sc.startColumn, "Unterminated comment.")
+			return
+		case sc.peek() == '/' && sc.peekNext() == '*':
+			sc.current += 2
+			depth++
+		case sc.peek() == '*' && sc.peekNext() == '/':
+			sc.current += 2
+			depth--
+		case sc.advance() == '\n':
+			sc.newLine()
+		}
+	}
+	if sc.keepComments {
+		sc.addTokenLiteral(COMMENT, sc.source[sc.start+2:sc.current-2])
+	}
+}
+
 // Return current character and move one character forward
 func (sc *Scanner) advance() byte {
 	ch := sc.source[sc.current]
@@ -201,6 +302,17 @@ func (sc *Scanner) peekNext() byte {
 	return sc.source[sc.current+1]
 }
 
+// Moves the line counter forward, must be called right after consuming '\n'.
+func (sc *Scanner) newLine() {
+	sc.line++
+	sc.lineStart = sc.current
+}
+
+// Return 1-based column of the current character
+func (sc *Scanner) column() int {
+	return sc.current - sc.lineStart + 1
+}
+
 func (sc *Scanner) isAtEnd() bool {
 	return sc.current >= len(sc.source)
 }
@@ -217,4 +329,4 @@ func isDigit(char byte) bool {
 	return char >= '0' && char <= '9'
 }
 
-type ErrorCallback func(line int, message string)
+type ErrorCallback func(line int, column int, message string)
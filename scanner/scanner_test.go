@@ -10,44 +10,44 @@ func TestScanTokens(t *testing.T) {
 		str   string
 		token Token
 	}{
-		{"(", Token{LEFT_PAREN, getStrPtr("("), nil, 1}},
-		{")", Token{RIGHT_PAREN, getStrPtr(")"), nil, 1}},
-		{"{", Token{LEFT_BRACE, getStrPtr("{"), nil, 1}},
-		{"}", Token{RIGHT_BRACE, getStrPtr("}"), nil, 1}},
-		{",", Token{COMMA, getStrPtr(","), nil, 1}},
-		{".", Token{DOT, getStrPtr("."), nil, 1}},
-		{"-", Token{MINUS, getStrPtr("-"), nil, 1}},
-		{"+", Token{PLUS, getStrPtr("+"), nil, 1}},
-		{";", Token{SEMICOLON, getStrPtr(";"), nil, 1}},
-		{"*", Token{STAR, getStrPtr("*"), nil, 1}},
-		{"/", Token{SLASH, getStrPtr("/"), nil, 1}},
-		{"!", Token{BANG, getStrPtr("!"), nil, 1}},
-		{"!=", Token{BANG_EQUAL, getStrPtr("!="), nil, 1}},
-		{"=", Token{EQUAL, getStrPtr("="), nil, 1}},
-		{"==", Token{EQUAL_EQUAL, getStrPtr("=="), nil, 1}},
-		{"<", Token{LESS, getStrPtr("<"), nil, 1}},
-		{"<=", Token{LESS_EQUAL, getStrPtr("<="), nil, 1}},
-		{">", Token{GREATER, getStrPtr(">"), nil, 1}},
-		{">=", Token{GREATER_EQUAL, getStrPtr(">="), nil, 1}},
-		{"123", Token{NUMBER, getStrPtr("123"), 123, 1}},
-		{"\"123\"", Token{STRING, getStrPtr("\"123\""), "123", 1}},
-		{"\"abc\"", Token{STRING, getStrPtr("\"abc\""), "abc", 1}},
-		{"and", Token{AND, getStrPtr("and"), nil, 1}},
-		{"class", Token{CLASS, getStrPtr("class"), nil, 1}},
-		{"else", Token{ELSE, getStrPtr("else"), nil, 1}},
-		{"false", Token{FALSE, getStrPtr("false"), nil, 1}},
-		{"for", Token{FOR, getStrPtr("for"), nil, 1}},
-		{"fun", Token{FUN, getStrPtr("fun"), nil, 1}},
-		{"if", Token{IF, getStrPtr("if"), nil, 1}},
-		{"nil", Token{NIL, getStrPtr("nil"), nil, 1}},
-		{"or", Token{OR, getStrPtr("or"), nil, 1}},
-		{"print", Token{PRINT, getStrPtr("print"), nil, 1}},
-		{"return", Token{RETURN, getStrPtr("return"), nil, 1}},
-		{"super", Token{SUPER, getStrPtr("super"), nil, 1}},
-		{"this", Token{THIS, getStrPtr("this"), nil, 1}},
-		{"true", Token{TRUE, getStrPtr("true"), nil, 1}},
-		{"var", Token{VAR, getStrPtr("var"), nil, 1}},
-		{"while", Token{WHILE, getStrPtr("while"), nil, 1}},
+		{"(", Token{LEFT_PAREN, getStrPtr("("), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{")", Token{RIGHT_PAREN, getStrPtr(")"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"{", Token{LEFT_BRACE, getStrPtr("{"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"}", Token{RIGHT_BRACE, getStrPtr("}"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{",", Token{COMMA, getStrPtr(","), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{".", Token{DOT, getStrPtr("."), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"-", Token{MINUS, getStrPtr("-"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"+", Token{PLUS, getStrPtr("+"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{";", Token{SEMICOLON, getStrPtr(";"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"*", Token{STAR, getStrPtr("*"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"/", Token{SLASH, getStrPtr("/"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"!", Token{BANG, getStrPtr("!"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"!=", Token{BANG_EQUAL, getStrPtr("!="), nil, 1, 1, 1, 3, 0, 2, ""}},
+		{"=", Token{EQUAL, getStrPtr("="), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"==", Token{EQUAL_EQUAL, getStrPtr("=="), nil, 1, 1, 1, 3, 0, 2, ""}},
+		{"<", Token{LESS, getStrPtr("<"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{"<=", Token{LESS_EQUAL, getStrPtr("<="), nil, 1, 1, 1, 3, 0, 2, ""}},
+		{">", Token{GREATER, getStrPtr(">"), nil, 1, 1, 1, 2, 0, 1, ""}},
+		{">=", Token{GREATER_EQUAL, getStrPtr(">="), nil, 1, 1, 1, 3, 0, 2, ""}},
+		{"123", Token{NUMBER, getStrPtr("123"), 123, 1, 1, 1, 4, 0, 3, ""}},
+		{"\"123\"", Token{STRING, getStrPtr("\"123\""), "123", 1, 1, 1, 6, 0, 5, ""}},
+		{"\"abc\"", Token{STRING, getStrPtr("\"abc\""), "abc", 1, 1, 1, 6, 0, 5, ""}},
+		{"and", Token{AND, getStrPtr("and"), nil, 1, 1, 1, 4, 0, 3, ""}},
+		{"class", Token{CLASS, getStrPtr("class"), nil, 1, 1, 1, 6, 0, 5, ""}},
+		{"else", Token{ELSE, getStrPtr("else"), nil, 1, 1, 1, 5, 0, 4, ""}},
+		{"false", Token{FALSE, getStrPtr("false"), nil, 1, 1, 1, 6, 0, 5, ""}},
+		{"for", Token{FOR, getStrPtr("for"), nil, 1, 1, 1, 4, 0, 3, ""}},
+		{"fun", Token{FUN, getStrPtr("fun"), nil, 1, 1, 1, 4, 0, 3, ""}},
+		{"if", Token{IF, getStrPtr("if"), nil, 1, 1, 1, 3, 0, 2, ""}},
+		{"nil", Token{NIL, getStrPtr("nil"), nil, 1, 1, 1, 4, 0, 3, ""}},
+		{"or", Token{OR, getStrPtr("or"), nil, 1, 1, 1, 3, 0, 2, ""}},
+		{"print", Token{PRINT, getStrPtr("print"), nil, 1, 1, 1, 6, 0, 5, ""}},
+		{"return", Token{RETURN, getStrPtr("return"), nil, 1, 1, 1, 7, 0, 6, ""}},
+		{"super", Token{SUPER, getStrPtr("super"), nil, 1, 1, 1, 6, 0, 5, ""}},
+		{"this", Token{THIS, getStrPtr("this"), nil, 1, 1, 1, 5, 0, 4, ""}},
+		{"true", Token{TRUE, getStrPtr("true"), nil, 1, 1, 1, 5, 0, 4, ""}},
+		{"var", Token{VAR, getStrPtr("var"), nil, 1, 1, 1, 4, 0, 3, ""}},
+		{"while", Token{WHILE, getStrPtr("while"), nil, 1, 1, 1, 6, 0, 5, ""}},
 	} {
 		errors := make([]string, 0)
 		sc := NewScanner(test.str, testCallBack(&errors))
@@ -69,6 +69,7 @@ func TestScanTokens(t *testing.T) {
 				t.Fatalf("expect: %v, got: %v, string: %v\n", test.token.Lexeme, gotToken.Lexeme, test.str)
 			}
 		}
+		validatePosition(t, test.token, gotToken, test.str)
 		// TODO : add compare literals
 
 		eofToken := got[1]
@@ -84,11 +85,43 @@ func TestScanTokensExpression(t *testing.T) {
 		tokens []Token
 	}{
 		{"(3 + 2", []Token{
-			{LEFT_PAREN, getStrPtr("("), nil, 1},
-			{NUMBER, getStrPtr("3"), 3, 1},
-			{PLUS, nil, nil, 1},
-			{NUMBER, getStrPtr("2"), 2, 1},
-			{EOF, nil, 2, 1},
+			{LEFT_PAREN, getStrPtr("("), nil, 1, 1, 1, 2, 0, 1, ""},
+			{NUMBER, getStrPtr("3"), 3, 1, 2, 1, 3, 1, 2, ""},
+			{PLUS, nil, nil, 1, 4, 1, 5, 3, 4, ""},
+			{NUMBER, getStrPtr("2"), 2, 1, 6, 1, 7, 5, 6, ""},
+			{EOF, nil, 2, 1, 7, 1, 7, 6, 6, ""},
+		},
+		},
+		{"{a: 1}", []Token{
+			{LEFT_BRACE, getStrPtr("{"), nil, 1, 1, 1, 2, 0, 1, ""},
+			{IDENTIFIER, getStrPtr("a"), nil, 1, 2, 1, 3, 1, 2, ""},
+			{COLON, getStrPtr(":"), nil, 1, 3, 1, 4, 2, 3, ""},
+			{NUMBER, getStrPtr("1"), 1, 1, 5, 1, 6, 4, 5, ""},
+			{RIGHT_BRACE, getStrPtr("}"), nil, 1, 6, 1, 7, 5, 6, ""},
+			{EOF, nil, nil, 1, 7, 1, 7, 6, 6, ""},
+		},
+		},
+		{"xs[0]", []Token{
+			{IDENTIFIER, getStrPtr("xs"), nil, 1, 1, 1, 3, 0, 2, ""},
+			{LEFT_BRACKET, getStrPtr("["), nil, 1, 3, 1, 4, 2, 3, ""},
+			{NUMBER, getStrPtr("0"), 0, 1, 4, 1, 5, 3, 4, ""},
+			{RIGHT_BRACKET, getStrPtr("]"), nil, 1, 5, 1, 6, 4, 5, ""},
+			{EOF, nil, nil, 1, 6, 1, 6, 5, 5, ""},
+		},
+		},
+		{"\"a${x}b\"", []Token{
+			{INTERPOLATION, getStrPtr("\"a${"), "a", 1, 1, 1, 5, 0, 4, ""},
+			{IDENTIFIER, getStrPtr("x"), nil, 1, 5, 1, 6, 4, 5, ""},
+			{STRING, getStrPtr("}b\""), "b", 1, 6, 1, 9, 5, 8, ""},
+			{EOF, nil, nil, 1, 9, 1, 9, 8, 8, ""},
+		},
+		},
+		{"\"${ {} }\"", []Token{
+			{INTERPOLATION, getStrPtr("\"${"), "", 1, 1, 1, 4, 0, 3, ""},
+			{LEFT_BRACE, getStrPtr("{"), nil, 1, 5, 1, 6, 4, 5, ""},
+			{RIGHT_BRACE, getStrPtr("}"), nil, 1, 6, 1, 7, 5, 6, ""},
+			{STRING, getStrPtr("}\""), "", 1, 8, 1, 10, 7, 9, ""},
+			{EOF, nil, nil, 1, 10, 1, 10, 9, 9, ""},
 		},
 		},
 	} {
@@ -102,6 +135,10 @@ func TestScanTokensExpression(t *testing.T) {
 			if got[i].TokenType != test.tokens[i].TokenType {
 				t.Fatalf("expect: %v got: %v", test.tokens[i].TokenType, got[i].TokenType)
 			}
+			if literal, ok := test.tokens[i].Literal.(string); ok && got[i].Literal != literal {
+				t.Fatalf("expect: %q got: %q", literal, got[i].Literal)
+			}
+			validatePosition(t, test.tokens[i], got[i], test.str)
 		}
 	}
 }
@@ -109,6 +146,7 @@ func TestScanTokensExpression(t *testing.T) {
 func TestScanTokensIgnored(t *testing.T) {
 	for _, test := range []string{
 		"// this is a comment", " ", "\r", "\t", "\n",
+		"/* block */", "/**/", "/* outer /* inner */ still outer */", "/* a\n * b\n */", "/* // */",
 	} {
 		errors := make([]string, 0)
 		sc := NewScanner(test, testCallBack(&errors))
@@ -123,6 +161,33 @@ func TestScanTokensIgnored(t *testing.T) {
 	}
 }
 
+func TestScanTokensKeepComments(t *testing.T) {
+	str := "// first\nvar a; // second\n//"
+	expect := []Token{
+		{COMMENT, getStrPtr("// first"), " first", 1, 1, 1, 9, 0, 8, ""},
+		{VAR, getStrPtr("var"), nil, 2, 1, 2, 4, 9, 12, ""},
+		{IDENTIFIER, getStrPtr("a"), nil, 2, 5, 2, 6, 13, 14, ""},
+		{SEMICOLON, getStrPtr(";"), nil, 2, 6, 2, 7, 14, 15, ""},
+		{COMMENT, getStrPtr("// second"), " second", 2, 8, 2, 17, 16, 25, ""},
+		{COMMENT, getStrPtr("//"), "", 3, 1, 3, 3, 26, 28, ""},
+		{EOF, nil, nil, 3, 3, 3, 3, 28, 28, ""},
+	}
+	errors := make([]string, 0)
+	got := NewScanner(str, testCallBack(&errors)).KeepComments().ScanTokens()
+	if len(got) != len(expect) {
+		t.Fatalf("expect len(%d), got: %d\n", len(expect), len(got))
+	}
+	for i := range got {
+		if got[i].TokenType != expect[i].TokenType || got[i].Literal != expect[i].Literal {
+			t.Errorf("expect: %v %q, got: %v %q", expect[i].TokenType, expect[i].Literal, got[i].TokenType, got[i].Literal)
+		}
+		if expect[i].Lexeme != nil && *got[i].Lexeme != *expect[i].Lexeme {
+			t.Errorf("expect lexeme: %q, got: %q", *expect[i].Lexeme, *got[i].Lexeme)
+		}
+		validatePosition(t, expect[i], got[i], str)
+	}
+}
+
 func TestScanTokensError(t *testing.T) {
 	for _, test := range []struct {
 		str    string
@@ -130,6 +195,9 @@ func TestScanTokensError(t *testing.T) {
 	}{
 		{"\"missing quote", []string{"[line 1] Error: Unterminated string."}},
 		{"~", []string{"[line 1] Error: Unexpected character."}},
+		{"/* open", []string{"[line 1] Error: Unterminated comment."}},
+		{"/* a /* b */", []string{"[line 1] Error: Unterminated comment."}},
+		{"\n/* a\n/* b */\n*", []string{"[line 2] Error: Unterminated comment."}},
 	} {
 		errors := make([]string, 0)
 		sc := NewScanner(test.str, testCallBack(&errors))
@@ -172,12 +240,100 @@ func TestScanTokenErrors(t *testing.T) {
 	}
 }
 
+func TestScanTokensPosition(t *testing.T) {
+	for _, test := range []struct {
+		str    string
+		tokens []Token
+	}{
+		{"var a =\n  \"x\ny\";", []Token{
+			{VAR, getStrPtr("var"), nil, 1, 1, 1, 4, 0, 3, ""},
+			{IDENTIFIER, getStrPtr("a"), nil, 1, 5, 1, 6, 4, 5, ""},
+			{EQUAL, getStrPtr("="), nil, 1, 7, 1, 8, 6, 7, ""},
+			{STRING, getStrPtr("\"x\ny\""), "x\ny", 2, 3, 3, 3, 10, 15, ""},
+			{SEMICOLON, getStrPtr(";"), nil, 3, 3, 3, 4, 15, 16, ""},
+			{EOF, nil, nil, 3, 4, 3, 4, 16, 16, ""},
+		},
+		},
+	} {
+		errors := make([]string, 0)
+		sc := NewScanner(test.str, testCallBack(&errors))
+		got := sc.ScanTokens()
+		if len(got) != len(test.tokens) {
+			t.Fatalf("expect len(%d), got: %d\n", len(test.tokens), len(got))
+		}
+		for i := range got {
+			validatePosition(t, test.tokens[i], got[i], test.str)
+		}
+	}
+}
+
+func TestScanTokensErrorPosition(t *testing.T) {
+	var gotLine, gotColumn int
+	sc := NewScanner("print 1;\n  @", func(line int, column int, message string) {
+		gotLine, gotColumn = line, column
+	})
+	sc.ScanTokens()
+	if gotLine != 2 || gotColumn != 3 {
+		t.Fatalf("expect: 2:3, got: %d:%d", gotLine, gotColumn)
+	}
+}
+
+func TestScanTokensBlockComment(t *testing.T) {
+	str := "/* a\n/* b\n*/ c */ x /* d */\ny"
+	expect := []Token{
+		{IDENTIFIER, getStrPtr("x"), nil, 3, 9, 3, 10, 18, 19, ""},
+		{IDENTIFIER, getStrPtr("y"), nil, 4, 1, 4, 2, 28, 29, ""},
+		{EOF, nil, nil, 4, 2, 4, 2, 29, 29, ""},
+	}
+	errors := make([]string, 0)
+	got := NewScanner(str, testCallBack(&errors)).ScanTokens()
+	if len(errors) != 0 {
+		t.Fatalf("expect no errors, got: %v", errors)
+	}
+	if len(got) != len(expect) {
+		t.Fatalf("expect len(%d), got: %d\n", len(expect), len(got))
+	}
+	for i := range got {
+		validatePosition(t, expect[i], got[i], str)
+	}
+
+	comments := NewScanner(str, testCallBack(&errors)).KeepComments().ScanTokens()
+	if len(comments) != 5 || comments[0].TokenType != COMMENT || comments[2].TokenType != COMMENT {
+		t.Fatalf("expect 2 comments, got: %v", comments)
+	}
+	if comments[0].Literal != " a\n/* b\n*/ c " || comments[2].Literal != " d " {
+		t.Errorf("expect the text of the comments, got: %q, %q", comments[0].Literal, comments[2].Literal)
+	}
+	validatePosition(t, Token{Line: 1, Column: 1, EndLine: 3, EndColumn: 8, Offset: 0, EndOffset: 17}, comments[0], str)
+}
+
+func TestScanTokensUnterminatedCommentPosition(t *testing.T) {
+	var gotLine, gotColumn int
+	sc := NewScanner("print 1;\n  /* a\n /* b */\n", func(line int, column int, message string) {
+		gotLine, gotColumn = line, column
+	})
+	sc.ScanTokens()
+	if gotLine != 2 || gotColumn != 3 {
+		t.Fatalf("expect: 2:3, got: %d:%d", gotLine, gotColumn)
+	}
+}
+
+func validatePosition(t *testing.T, expect Token, got Token, str string) {
+	if got.Line != expect.Line || got.Column != expect.Column || got.EndLine != expect.EndLine || got.EndColumn != expect.EndColumn {
+		t.Fatalf("expect: %d:%d-%d:%d, got: %d:%d-%d:%d, string: %v\n",
+			expect.Line, expect.Column, expect.EndLine, expect.EndColumn, got.Line, got.Column, got.EndLine, got.EndColumn, str)
+	}
+	if got.Offset != expect.Offset || got.EndOffset != expect.EndOffset {
+		t.Fatalf("expect: [%d, %d), got: [%d, %d), string: %v\n", expect.Offset, expect.EndOffset, got.Offset, got.EndOffset, str)
+	}
+}
+
 func getStrPtr(s string) *string {
 	return &s
 }
 
 var testCallBack = func(errs *[]string) ErrorCallback {
-	return func(line int, message string) {
+	return func(line int, _ int, message string) {
 		*errs = append(*errs, fmt.Sprintf("[line %d] Error: %v", line, message))
 	}
 }
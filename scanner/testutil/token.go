@@ -30,6 +30,41 @@ func For() scanner.Token {
 	return scanner.Token{TokenType: scanner.FOR, Lexeme: &lexeme, Line: 1}
 }
 
+func Fun() scanner.Token {
+	lexeme := "fun"
+	return scanner.Token{TokenType: scanner.FUN, Lexeme: &lexeme, Line: 1}
+}
+
+func Class() scanner.Token {
+	lexeme := "class"
+	return scanner.Token{TokenType: scanner.CLASS, Lexeme: &lexeme, Line: 1}
+}
+
+func Try() scanner.Token {
+	lexeme := "try"
+	return scanner.Token{TokenType: scanner.TRY, Lexeme: &lexeme, Line: 1}
+}
+
+func Catch() scanner.Token {
+	lexeme := "catch"
+	return scanner.Token{TokenType: scanner.CATCH, Lexeme: &lexeme, Line: 1}
+}
+
+func Finally() scanner.Token {
+	lexeme := "finally"
+	return scanner.Token{TokenType: scanner.FINALLY, Lexeme: &lexeme, Line: 1}
+}
+
+func Throw() scanner.Token {
+	lexeme := "throw"
+	return scanner.Token{TokenType: scanner.THROW, Lexeme: &lexeme, Line: 1}
+}
+
+func Import() scanner.Token {
+	lexeme := "import"
+	return scanner.Token{TokenType: scanner.IMPORT, Lexeme: &lexeme, Line: 1}
+}
+
 func VarDecl() scanner.Token {
 	lexeme := "var"
 	return scanner.Token{TokenType: scanner.VAR, Lexeme: &lexeme, Line: 1}
@@ -44,6 +79,26 @@ func Identifier(name string) scanner.Token {
 	return scanner.Token{TokenType: scanner.IDENTIFIER, Lexeme: &name, Literal: name, Line: 1}
 }
 
+func LeftBracket() scanner.Token {
+	lexeme := "["
+	return scanner.Token{TokenType: scanner.LEFT_BRACKET, Lexeme: &lexeme, Line: 1}
+}
+
+func RightBracket() scanner.Token {
+	lexeme := "]"
+	return scanner.Token{TokenType: scanner.RIGHT_BRACKET, Lexeme: &lexeme, Line: 1}
+}
+
+func Comma() scanner.Token {
+	lexeme := ","
+	return scanner.Token{TokenType: scanner.COMMA, Lexeme: &lexeme, Line: 1}
+}
+
+func Colon() scanner.Token {
+	lexeme := ":"
+	return scanner.Token{TokenType: scanner.COLON, Lexeme: &lexeme, Line: 1}
+}
+
 func LeftParen() scanner.Token {
 	lexeme := "("
 	return scanner.Token{TokenType: scanner.LEFT_PAREN, Lexeme: &lexeme, Line: 1}
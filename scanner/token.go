@@ -15,7 +15,10 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
+	COLON
 	DOT
 	MINUS
 	PLUS
@@ -36,26 +39,38 @@ const (
 	// Literals.
 	IDENTIFIER
 	STRING
+	// String segment followed by an interpolated expression: "text ${ or }text ${
+	INTERPOLATION
 	NUMBER
 
 	// Keywords.
 	AND
+	BREAK
+	CATCH
 	CLASS
+	CONTINUE
 	ELSE
 	FALSE
+	FINALLY
 	FUN
 	FOR
 	IF
+	IMPORT
 	NIL
 	OR
 	PRINT
 	RETURN
 	SUPER
 	THIS
+	THROW
 	TRUE
+	TRY
 	VAR
 	WHILE
 
+	// Emitted only by a scanner that keeps comments.
+	COMMENT
+
 	EOF
 )
 
@@ -64,6 +79,14 @@ type Token struct {
 	Lexeme    *string
 	Literal   interface{}
 	Line      int
+	// 1-based column of the first character of the token.
+	Column int
+	// Line and column right after the last character of the token.
+	EndLine, EndColumn int
+	// Byte offsets of the token in the source: [Offset, EndOffset).
+	Offset, EndOffset int
+	// Path of the imported file the token comes from, empty for the program itself.
+	File string
 }
 
 func (t Token) String() string {
@@ -12,46 +12,58 @@ func _() {
 	_ = x[RIGHT_PAREN-1]
 	_ = x[LEFT_BRACE-2]
 	_ = x[RIGHT_BRACE-3]
-	_ = x[COMMA-4]
-	_ = x[DOT-5]
-	_ = x[MINUS-6]
-	_ = x[PLUS-7]
-	_ = x[SEMICOLON-8]
-	_ = x[SLASH-9]
-	_ = x[STAR-10]
-	_ = x[BANG-11]
-	_ = x[BANG_EQUAL-12]
-	_ = x[EQUAL-13]
-	_ = x[EQUAL_EQUAL-14]
-	_ = x[GREATER-15]
-	_ = x[GREATER_EQUAL-16]
-	_ = x[LESS-17]
-	_ = x[LESS_EQUAL-18]
-	_ = x[IDENTIFIER-19]
-	_ = x[STRING-20]
-	_ = x[NUMBER-21]
-	_ = x[AND-22]
-	_ = x[CLASS-23]
-	_ = x[ELSE-24]
-	_ = x[FALSE-25]
-	_ = x[FUN-26]
-	_ = x[FOR-27]
-	_ = x[IF-28]
-	_ = x[NIL-29]
-	_ = x[OR-30]
-	_ = x[PRINT-31]
-	_ = x[RETURN-32]
-	_ = x[SUPER-33]
-	_ = x[THIS-34]
-	_ = x[TRUE-35]
-	_ = x[VAR-36]
-	_ = x[WHILE-37]
-	_ = x[EOF-38]
+	_ = x[LEFT_BRACKET-4]
+	_ = x[RIGHT_BRACKET-5]
+	_ = x[COMMA-6]
+	_ = x[COLON-7]
+	_ = x[DOT-8]
+	_ = x[MINUS-9]
+	_ = x[PLUS-10]
+	_ = x[SEMICOLON-11]
+	_ = x[SLASH-12]
+	_ = x[STAR-13]
+	_ = x[BANG-14]
+	_ = x[BANG_EQUAL-15]
+	_ = x[EQUAL-16]
+	_ = x[EQUAL_EQUAL-17]
+	_ = x[GREATER-18]
+	_ = x[GREATER_EQUAL-19]
+	_ = x[LESS-20]
+	_ = x[LESS_EQUAL-21]
+	_ = x[IDENTIFIER-22]
+	_ = x[STRING-23]
+	_ = x[INTERPOLATION-24]
+	_ = x[NUMBER-25]
+	_ = x[AND-26]
+	_ = x[BREAK-27]
+	_ = x[CATCH-28]
+	_ = x[CLASS-29]
+	_ = x[CONTINUE-30]
+	_ = x[ELSE-31]
+	_ = x[FALSE-32]
+	_ = x[FINALLY-33]
+	_ = x[FUN-34]
+	_ = x[FOR-35]
+	_ = x[IF-36]
+	_ = x[IMPORT-37]
+	_ = x[NIL-38]
+	_ = x[OR-39]
+	_ = x[PRINT-40]
+	_ = x[RETURN-41]
+	_ = x[SUPER-42]
+	_ = x[THIS-43]
+	_ = x[THROW-44]
+	_ = x[TRUE-45]
+	_ = x[TRY-46]
+	_ = x[VAR-47]
+	_ = x[WHILE-48]
+	_ = x[COMMENT-49]
+	_ = x[EOF-50]
 }
 
-const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISTRUEVARWHILEEOF"
+const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACELEFT_BRACKETRIGHT_BRACKETCOMMACOLONDOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGINTERPOLATIONNUMBERANDBREAKCATCHCLASSCONTINUEELSEFALSEFINALLYFUNFORIFIMPORTNILORPRINTRETURNSUPERTHISTHROWTRUETRYVARWHILECOMMENTEOF"
 
-var _TokenType_index = [...]uint8{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 151, 157, 163, 166, 171, 175, 180, 183, 186, 188, 191, 193, 198, 204, 209, 213, 217, 220, 225, 228}
+var _TokenType_index = [...]uint16{0, 10, 21, 31, 42, 54, 67, 72, 77, 80, 85, 89, 98, 103, 107, 111, 121, 126, 137, 144, 157, 161, 171, 181, 187, 200, 206, 209, 214, 219, 224, 232, 236, 241, 248, 251, 254, 256, 262, 265, 267, 272, 278, 283, 287, 292, 296, 299, 302, 307, 314, 317}
 
 func (i TokenType) String() string {
 	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {
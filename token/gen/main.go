@@ -34,17 +34,28 @@ var expressions = []string{
 	"VariableExpr: Name scanner.Token",
 	"BinaryExpr: Left Expr, Operator scanner.Token, Right Expr",
 	"GroupingExpr: Expression Expr",
+	"LambdaExpr: Function *FunctionStmt",
+	"ListExpr: Bracket *scanner.Token, Elements []Expr",
+	"MapExpr: Brace *scanner.Token, Keys []Expr, Values []Expr",
+	"IndexExpr: Object Expr, Bracket *scanner.Token, Index Expr",
+	"IndexSetExpr: Object Expr, Bracket *scanner.Token, Index Expr, Value Expr",
+	"InterpolationExpr: Start *scanner.Token, Parts []Expr",
 }
 
 var statements = []string{
 	"BlockStmt: Statements []Stmt",
-	"ClassStmt: Name *scanner.Token, Superclass *VariableExpr, Methods []*FunctionStmt",
+	"BreakStmt: Keyword *scanner.Token",
+	"ContinueStmt: Keyword *scanner.Token",
+	"ClassStmt: Name *scanner.Token, Superclass *VariableExpr, Methods []*FunctionStmt, ClassMethods []*FunctionStmt",
 	"ExpressionStmt: Expression Expr",
-	"FunctionStmt: Name *scanner.Token, Params []*scanner.Token, Body []Stmt",
+	"FunctionStmt: Name *scanner.Token, Params []*scanner.Token, Body []Stmt, IsGetter bool",
 	"IfStmt: Condition Expr, ThenBranch Stmt, ElseBranch Stmt",
+	"ImportStmt: Keyword *scanner.Token, Path *scanner.Token, Name *scanner.Token, Names []*scanner.Token",
 	"PrintStmt: Expression Expr",
 	"ReturnStmt: Keyword *scanner.Token, Value Expr",
-	"WhileStmt: Condition Expr, Body Stmt",
+	"ThrowStmt: Keyword *scanner.Token, Value Expr",
+	"TryStmt: Keyword *scanner.Token, Body []Stmt, CatchName *scanner.Token, CatchBody []Stmt, FinallyBody []Stmt",
+	"WhileStmt: Condition Expr, Body Stmt, Increment Expr",
 	"VarStmt: Name scanner.Token, Initializer Expr",
 }
 
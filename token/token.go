@@ -23,6 +23,12 @@ type VisitorExpr interface {
 	VisitVariableExpr(expr *VariableExpr) (interface{}, error)
 	VisitBinaryExpr(expr *BinaryExpr) (interface{}, error)
 	VisitGroupingExpr(expr *GroupingExpr) (interface{}, error)
+	VisitLambdaExpr(expr *LambdaExpr) (interface{}, error)
+	VisitListExpr(expr *ListExpr) (interface{}, error)
+	VisitMapExpr(expr *MapExpr) (interface{}, error)
+	VisitIndexExpr(expr *IndexExpr) (interface{}, error)
+	VisitIndexSetExpr(expr *IndexSetExpr) (interface{}, error)
+	VisitInterpolationExpr(expr *InterpolationExpr) (interface{}, error)
 }
 
 type AssignExpr struct {
@@ -133,18 +139,80 @@ func (e *GroupingExpr) Accept(visitor VisitorExpr) (interface{}, error) {
 	return visitor.VisitGroupingExpr(e)
 }
 
+type LambdaExpr struct {
+	Function *FunctionStmt
+}
+
+func (e *LambdaExpr) Accept(visitor VisitorExpr) (interface{}, error) {
+	return visitor.VisitLambdaExpr(e)
+}
+
+type ListExpr struct {
+	Bracket *scanner.Token
+	Elements []Expr
+}
+
+func (e *ListExpr) Accept(visitor VisitorExpr) (interface{}, error) {
+	return visitor.VisitListExpr(e)
+}
+
+type MapExpr struct {
+	Brace *scanner.Token
+	Keys []Expr
+	Values []Expr
+}
+
+func (e *MapExpr) Accept(visitor VisitorExpr) (interface{}, error) {
+	return visitor.VisitMapExpr(e)
+}
+
+type IndexExpr struct {
+	Object Expr
+	Bracket *scanner.Token
+	Index Expr
+}
+
+func (e *IndexExpr) Accept(visitor VisitorExpr) (interface{}, error) {
+	return visitor.VisitIndexExpr(e)
+}
+
+type IndexSetExpr struct {
+	Object Expr
+	Bracket *scanner.Token
+	Index Expr
+	Value Expr
+}
+
+func (e *IndexSetExpr) Accept(visitor VisitorExpr) (interface{}, error) {
+	return visitor.VisitIndexSetExpr(e)
+}
+
+type InterpolationExpr struct {
+	Start *scanner.Token
+	Parts []Expr
+}
+
+func (e *InterpolationExpr) Accept(visitor VisitorExpr) (interface{}, error) {
+	return visitor.VisitInterpolationExpr(e)
+}
+
 type Stmt interface {
 	Accept(visitor VisitorStmt) (interface{}, error)
 }
 
 type VisitorStmt interface {
 	VisitBlockStmt(stmt *BlockStmt) (interface{}, error)
+	VisitBreakStmt(stmt *BreakStmt) (interface{}, error)
+	VisitContinueStmt(stmt *ContinueStmt) (interface{}, error)
 	VisitClassStmt(stmt *ClassStmt) (interface{}, error)
 	VisitExpressionStmt(stmt *ExpressionStmt) (interface{}, error)
 	VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error)
 	VisitIfStmt(stmt *IfStmt) (interface{}, error)
+	VisitImportStmt(stmt *ImportStmt) (interface{}, error)
 	VisitPrintStmt(stmt *PrintStmt) (interface{}, error)
 	VisitReturnStmt(stmt *ReturnStmt) (interface{}, error)
+	VisitThrowStmt(stmt *ThrowStmt) (interface{}, error)
+	VisitTryStmt(stmt *TryStmt) (interface{}, error)
 	VisitWhileStmt(stmt *WhileStmt) (interface{}, error)
 	VisitVarStmt(stmt *VarStmt) (interface{}, error)
 }
@@ -157,10 +225,27 @@ func (e *BlockStmt) Accept(visitor VisitorStmt) (interface{}, error) {
 	return visitor.VisitBlockStmt(e)
 }
 
+type BreakStmt struct {
+	Keyword *scanner.Token
+}
+
+func (e *BreakStmt) Accept(visitor VisitorStmt) (interface{}, error) {
+	return visitor.VisitBreakStmt(e)
+}
+
+type ContinueStmt struct {
+	Keyword *scanner.Token
+}
+
+func (e *ContinueStmt) Accept(visitor VisitorStmt) (interface{}, error) {
+	return visitor.VisitContinueStmt(e)
+}
+
 type ClassStmt struct {
 	Name *scanner.Token
 	Superclass *VariableExpr
 	Methods []*FunctionStmt
+	ClassMethods []*FunctionStmt
 }
 
 func (e *ClassStmt) Accept(visitor VisitorStmt) (interface{}, error) {
@@ -179,6 +264,7 @@ type FunctionStmt struct {
 	Name *scanner.Token
 	Params []*scanner.Token
 	Body []Stmt
+	IsGetter bool
 }
 
 func (e *FunctionStmt) Accept(visitor VisitorStmt) (interface{}, error) {
@@ -195,6 +281,17 @@ func (e *IfStmt) Accept(visitor VisitorStmt) (interface{}, error) {
 	return visitor.VisitIfStmt(e)
 }
 
+type ImportStmt struct {
+	Keyword *scanner.Token
+	Path *scanner.Token
+	Name *scanner.Token
+	Names []*scanner.Token
+}
+
+func (e *ImportStmt) Accept(visitor VisitorStmt) (interface{}, error) {
+	return visitor.VisitImportStmt(e)
+}
+
 type PrintStmt struct {
 	Expression Expr
 }
@@ -212,9 +309,31 @@ func (e *ReturnStmt) Accept(visitor VisitorStmt) (interface{}, error) {
 	return visitor.VisitReturnStmt(e)
 }
 
+type ThrowStmt struct {
+	Keyword *scanner.Token
+	Value Expr
+}
+
+func (e *ThrowStmt) Accept(visitor VisitorStmt) (interface{}, error) {
+	return visitor.VisitThrowStmt(e)
+}
+
+type TryStmt struct {
+	Keyword *scanner.Token
+	Body []Stmt
+	CatchName *scanner.Token
+	CatchBody []Stmt
+	FinallyBody []Stmt
+}
+
+func (e *TryStmt) Accept(visitor VisitorStmt) (interface{}, error) {
+	return visitor.VisitTryStmt(e)
+}
+
 type WhileStmt struct {
 	Condition Expr
 	Body Stmt
+	Increment Expr
 }
 
 func (e *WhileStmt) Accept(visitor VisitorStmt) (interface{}, error) {
@@ -0,0 +1,101 @@
+package vm
+
+import (
+	"github.com/nesyuk/golox/scanner"
+	"math"
+)
+
+type OpCode byte
+
+const (
+	OP_CONSTANT OpCode = iota
+	OP_NIL
+	OP_TRUE
+	OP_FALSE
+	OP_POP
+	OP_GET_LOCAL
+	OP_SET_LOCAL
+	OP_GET_GLOBAL
+	OP_DEFINE_GLOBAL
+	OP_SET_GLOBAL
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	OP_GET_SUPER
+	OP_EQUAL
+	OP_NOT_EQUAL
+	OP_GREATER
+	OP_GREATER_EQUAL
+	OP_LESS
+	OP_LESS_EQUAL
+	OP_ADD
+	OP_SUBTRACT
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_NOT
+	OP_NEGATE
+	OP_PRINT
+	OP_JUMP
+	OP_JUMP_IF_FALSE
+	OP_LOOP
+	OP_CALL
+	OP_CLOSURE
+	OP_CLOSE_UPVALUE
+	OP_RETURN
+	OP_IMPORT
+	OP_CLASS
+	OP_INHERIT
+	OP_METHOD
+	OP_CLASS_METHOD
+	OP_LIST
+	OP_INTERPOLATE
+	OP_MAP
+	OP_GET_INDEX
+	OP_SET_INDEX
+	OP_TRY
+	OP_TRY_FINALLY
+	OP_POP_TRY
+	OP_THROW
+	OP_RETHROW
+)
+
+// Chunk is the compiled bytecode of a single function.
+type Chunk struct {
+	Code      []byte
+	Constants []interface{}
+	// Source token of every byte in Code, used to report runtime errors.
+	Tokens []*scanner.Token
+	// Indexes of the number and string constants, they are added once.
+	numbers map[uint64]int
+	strings map[string]int
+}
+
+func (c *Chunk) write(b byte, tok *scanner.Token) {
+	c.Code = append(c.Code, b)
+	c.Tokens = append(c.Tokens, tok)
+}
+
+func (c *Chunk) addConstant(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		// By bits, so NaN is found again and -0 is kept apart from 0.
+		if c.numbers == nil {
+			c.numbers = make(map[uint64]int)
+		}
+		if idx, exist := c.numbers[math.Float64bits(v)]; exist {
+			return idx
+		}
+		c.numbers[math.Float64bits(v)] = len(c.Constants)
+	case string:
+		if c.strings == nil {
+			c.strings = make(map[string]int)
+		}
+		if idx, exist := c.strings[v]; exist {
+			return idx
+		}
+		c.strings[v] = len(c.Constants)
+	}
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
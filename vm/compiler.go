@@ -0,0 +1,885 @@
+package vm
+
+import (
+	"github.com/nesyuk/golox/scanner"
+	"github.com/nesyuk/golox/token"
+	"math"
+)
+
+const (
+	maxLocals   = math.MaxUint8 + 1
+	maxUpvalues = math.MaxUint8 + 1
+)
+
+type functionType uint8
+
+const (
+	TYPE_SCRIPT functionType = iota
+	TYPE_FUNCTION
+	TYPE_METHOD
+	TYPE_INITIALIZER
+)
+
+type local struct {
+	name       string
+	depth      int
+	isCaptured bool
+}
+
+type upvalueRef struct {
+	index   uint8
+	isLocal bool
+}
+
+// functionCompiler holds the state of the function being compiled, it is chained to the enclosing function.
+type functionCompiler struct {
+	enclosing  *functionCompiler
+	function   *Function
+	fnType     functionType
+	locals     []local
+	upvalues   []upvalueRef
+	scopeDepth int
+	loop       *loopCompiler
+	try        *tryCompiler
+}
+
+// loopCompiler collects the jumps of break and continue statements of the innermost loop.
+type loopCompiler struct {
+	enclosing     *loopCompiler
+	scopeDepth    int
+	breakJumps    []int
+	continueJumps []int
+}
+
+// tryCompiler is a region of a try statement with an exception handler installed,
+// return, break and continue remove the handler and run the finally clause when they leave the region.
+type tryCompiler struct {
+	enclosing *tryCompiler
+	loop      *loopCompiler
+	// Nil for the region of the catch handler.
+	finally []token.Stmt
+}
+
+type classCompiler struct {
+	enclosing     *classCompiler
+	hasSuperclass bool
+}
+
+// Compiler translates a resolved AST into bytecode.
+type Compiler struct {
+	current       *functionCompiler
+	currentClass  *classCompiler
+	locals        map[token.Expr]int
+	errorCallback ErrorCallback
+}
+
+func NewCompiler(onError ErrorCallback) *Compiler {
+	return &Compiler{locals: make(map[token.Expr]int), errorCallback: onError}
+}
+
+// Resolve records a local variable reference, so the compiler is fed by resolver.Resolve.
+func (c *Compiler) Resolve(expr token.Expr, depth int) {
+	c.locals[expr] = depth
+}
+
+func (c *Compiler) Compile(stmts []token.Stmt) (*Function, error) {
+	c.beginFunction(TYPE_SCRIPT, "")
+	for _, stmt := range stmts {
+		if _, err := c.compileStmt(stmt); err != nil {
+			return nil, err
+		}
+	}
+	fn, _ := c.endFunction(nil)
+	return fn, nil
+}
+
+func (c *Compiler) beginFunction(fnType functionType, name string) {
+	c.current = &functionCompiler{
+		enclosing: c.current,
+		function:  &Function{name: name},
+		fnType:    fnType,
+	}
+	// The first slot holds the called function or 'this' inside methods.
+	slotName := ""
+	if fnType == TYPE_METHOD || fnType == TYPE_INITIALIZER {
+		slotName = "this"
+	}
+	c.current.locals = append(c.current.locals, local{name: slotName})
+}
+
+func (c *Compiler) endFunction(tok *scanner.Token) (*Function, []upvalueRef) {
+	c.emitReturn(tok)
+	fc := c.current
+	fc.function.upvalueCount = len(fc.upvalues)
+	c.current = fc.enclosing
+	return fc.function, fc.upvalues
+}
+
+func (c *Compiler) compileStmt(stmt token.Stmt) (interface{}, error) {
+	return stmt.Accept(c)
+}
+
+func (c *Compiler) compileExpr(expr token.Expr) (interface{}, error) {
+	return expr.Accept(c)
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return &c.current.function.chunk
+}
+
+func (c *Compiler) emit(tok *scanner.Token, bytes ...byte) {
+	for _, b := range bytes {
+		c.chunk().write(b, tok)
+	}
+}
+
+func (c *Compiler) emitOp(op OpCode, tok *scanner.Token) {
+	c.emit(tok, byte(op))
+}
+
+func (c *Compiler) emitShortOp(op OpCode, operand int, tok *scanner.Token) {
+	c.emit(tok, byte(op), byte(operand>>8), byte(operand))
+}
+
+func (c *Compiler) emitConstant(value interface{}, tok *scanner.Token) {
+	c.emitShortOp(OP_CONSTANT, c.makeConstant(value, tok), tok)
+}
+
+func (c *Compiler) makeConstant(value interface{}, tok *scanner.Token) int {
+	idx := c.chunk().addConstant(value)
+	if idx > math.MaxUint16 {
+		c.error(tok, "Too many constants in one chunk.")
+		return 0
+	}
+	return idx
+}
+
+func (c *Compiler) emitReturn(tok *scanner.Token) {
+	c.emitDefaultReturnValue(tok)
+	c.emitOp(OP_RETURN, tok)
+}
+
+func (c *Compiler) emitDefaultReturnValue(tok *scanner.Token) {
+	if c.current.fnType == TYPE_INITIALIZER {
+		c.emit(tok, byte(OP_GET_LOCAL), 0)
+	} else {
+		c.emitOp(OP_NIL, tok)
+	}
+}
+
+// emitJump writes a jump with a placeholder offset and returns the position to patch.
+func (c *Compiler) emitJump(op OpCode, tok *scanner.Token) int {
+	c.emitShortOp(op, 0xffff, tok)
+	return len(c.chunk().Code) - 2
+}
+
+func (c *Compiler) patchJump(offset int, tok *scanner.Token) {
+	jump := len(c.chunk().Code) - offset - 2
+	if jump > math.MaxUint16 {
+		c.error(tok, "Too much code to jump over.")
+	}
+	c.chunk().Code[offset] = byte(jump >> 8)
+	c.chunk().Code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) emitLoop(loopStart int, tok *scanner.Token) {
+	offset := len(c.chunk().Code) - loopStart + 3
+	if offset > math.MaxUint16 {
+		c.error(tok, "Loop body too large.")
+	}
+	c.emitShortOp(OP_LOOP, offset, tok)
+}
+
+func (c *Compiler) error(tok *scanner.Token, message string) {
+	if tok == nil {
+		tok = &scanner.Token{TokenType: scanner.EOF}
+	}
+	c.errorCallback(*tok, message)
+}
+
+func (c *Compiler) beginScope() {
+	c.current.scopeDepth++
+}
+
+func (c *Compiler) endScope(tok *scanner.Token) {
+	fc := c.current
+	fc.scopeDepth--
+	for len(fc.locals) > 0 && fc.locals[len(fc.locals)-1].depth > fc.scopeDepth {
+		if fc.locals[len(fc.locals)-1].isCaptured {
+			c.emitOp(OP_CLOSE_UPVALUE, tok)
+		} else {
+			c.emitOp(OP_POP, tok)
+		}
+		fc.locals = fc.locals[:len(fc.locals)-1]
+	}
+}
+
+func (c *Compiler) addLocal(name *scanner.Token) {
+	if len(c.current.locals) >= maxLocals {
+		c.error(name, "Too many local variables in function.")
+		return
+	}
+	c.current.locals = append(c.current.locals, local{name: *name.Lexeme, depth: c.current.scopeDepth})
+}
+
+// defineVariable binds the value on top of the stack to a new local or global variable.
+func (c *Compiler) defineVariable(name *scanner.Token) {
+	if c.current.scopeDepth > 0 {
+		c.addLocal(name)
+		return
+	}
+	c.emitShortOp(OP_DEFINE_GLOBAL, c.makeConstant(*name.Lexeme, name), name)
+}
+
+func resolveLocal(fc *functionCompiler, name string) int {
+	for i := len(fc.locals) - 1; i >= 0; i-- {
+		if fc.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) resolveUpvalue(fc *functionCompiler, name *scanner.Token) int {
+	if fc.enclosing == nil {
+		return -1
+	}
+	if idx := resolveLocal(fc.enclosing, *name.Lexeme); idx != -1 {
+		fc.enclosing.locals[idx].isCaptured = true
+		return c.addUpvalue(fc, uint8(idx), true, name)
+	}
+	if idx := c.resolveUpvalue(fc.enclosing, name); idx != -1 {
+		return c.addUpvalue(fc, uint8(idx), false, name)
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(fc *functionCompiler, index uint8, isLocal bool, name *scanner.Token) int {
+	for i, up := range fc.upvalues {
+		if up.index == index && up.isLocal == isLocal {
+			return i
+		}
+	}
+	if len(fc.upvalues) >= maxUpvalues {
+		c.error(name, "Too many closure variables in function.")
+		return 0
+	}
+	fc.upvalues = append(fc.upvalues, upvalueRef{index, isLocal})
+	return len(fc.upvalues) - 1
+}
+
+// namedVariable emits a read, or a write when value is not nil, of a variable.
+// Only expressions recorded by the resolver are looked up among locals, everything else is a global.
+func (c *Compiler) namedVariable(expr token.Expr, name *scanner.Token, value token.Expr) error {
+	getOp, setOp := OP_GET_GLOBAL, OP_SET_GLOBAL
+	arg := -1
+	if _, isLocal := c.locals[expr]; isLocal {
+		if arg = resolveLocal(c.current, *name.Lexeme); arg != -1 {
+			getOp, setOp = OP_GET_LOCAL, OP_SET_LOCAL
+		} else if arg = c.resolveUpvalue(c.current, name); arg != -1 {
+			getOp, setOp = OP_GET_UPVALUE, OP_SET_UPVALUE
+		}
+	}
+
+	op := getOp
+	if value != nil {
+		if _, err := c.compileExpr(value); err != nil {
+			return err
+		}
+		op = setOp
+	}
+	if arg == -1 {
+		c.emitShortOp(op, c.makeConstant(*name.Lexeme, name), name)
+	} else {
+		c.emit(name, byte(op), byte(arg))
+	}
+	return nil
+}
+
+func (c *Compiler) function(stmt *token.FunctionStmt, fnType functionType) error {
+	c.beginFunction(fnType, *stmt.Name.Lexeme)
+	// Lambdas are named after their 'fun' keyword.
+	c.current.function.anonymous = stmt.Name.TokenType == scanner.FUN
+	c.current.function.getter = stmt.IsGetter
+	c.beginScope()
+	c.current.function.arity = len(stmt.Params)
+	for _, param := range stmt.Params {
+		c.addLocal(param)
+	}
+	for _, s := range stmt.Body {
+		if _, err := c.compileStmt(s); err != nil {
+			return err
+		}
+	}
+	fn, upvalues := c.endFunction(stmt.Name)
+
+	c.emitShortOp(OP_CLOSURE, c.makeConstant(fn, stmt.Name), stmt.Name)
+	for _, up := range upvalues {
+		isLocal := byte(0)
+		if up.isLocal {
+			isLocal = 1
+		}
+		c.emit(stmt.Name, isLocal, up.index)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitBlockStmt(stmt *token.BlockStmt) (interface{}, error) {
+	return nil, c.compileBlock(stmt.Statements)
+}
+
+func (c *Compiler) compileBlock(stmts []token.Stmt) error {
+	c.beginScope()
+	for _, s := range stmts {
+		if _, err := c.compileStmt(s); err != nil {
+			return err
+		}
+	}
+	c.endScope(nil)
+	return nil
+}
+
+func (c *Compiler) VisitImportStmt(stmt *token.ImportStmt) (interface{}, error) {
+	path := c.makeConstant(stmt.Path.Literal.(string), stmt.Path)
+	if stmt.Name != nil {
+		c.emitShortOp(OP_IMPORT, path, stmt.Path)
+		c.defineVariable(stmt.Name)
+		return nil, nil
+	}
+	// Modules run once, every name imports the cached namespace again.
+	for _, name := range stmt.Names {
+		c.emitShortOp(OP_IMPORT, path, stmt.Path)
+		c.emitShortOp(OP_GET_PROPERTY, c.makeConstant(*name.Lexeme, name), name)
+		c.defineVariable(name)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitClassStmt(stmt *token.ClassStmt) (interface{}, error) {
+	nameConstant := c.makeConstant(*stmt.Name.Lexeme, stmt.Name)
+	c.emitShortOp(OP_CLASS, nameConstant, stmt.Name)
+	c.defineVariable(stmt.Name)
+	ref := c.classRef(stmt)
+
+	cls := &classCompiler{enclosing: c.currentClass}
+	c.currentClass = cls
+
+	if stmt.Superclass != nil {
+		if _, err := c.compileExpr(stmt.Superclass); err != nil {
+			return nil, err
+		}
+		// The superclass stays on the stack as a local named 'super' captured by the methods.
+		c.beginScope()
+		c.addLocal(syntheticToken(scanner.SUPER, "super", stmt.Superclass.Name))
+		if err := c.namedVariable(ref, stmt.Name, nil); err != nil {
+			return nil, err
+		}
+		c.emitOp(OP_INHERIT, &stmt.Superclass.Name)
+		cls.hasSuperclass = true
+	}
+
+	// Keep the class on the stack while its methods are attached.
+	if err := c.namedVariable(ref, stmt.Name, nil); err != nil {
+		return nil, err
+	}
+	for _, method := range stmt.Methods {
+		fnType := TYPE_METHOD
+		if *method.Name.Lexeme == "init" {
+			fnType = TYPE_INITIALIZER
+		}
+		if err := c.function(method, fnType); err != nil {
+			return nil, err
+		}
+		c.emitShortOp(OP_METHOD, c.makeConstant(*method.Name.Lexeme, method.Name), method.Name)
+	}
+	// Class methods can't use 'this', they are called with the closure in slot zero like functions.
+	for _, method := range stmt.ClassMethods {
+		if err := c.function(method, TYPE_FUNCTION); err != nil {
+			return nil, err
+		}
+		c.emitShortOp(OP_CLASS_METHOD, c.makeConstant(*method.Name.Lexeme, method.Name), method.Name)
+	}
+	c.emitOp(OP_POP, stmt.Name)
+
+	if cls.hasSuperclass {
+		c.endScope(stmt.Name)
+	}
+	c.currentClass = cls.enclosing
+	return nil, nil
+}
+
+// classRef returns an expression that resolves like the class name inside the class body:
+// a local when the class is declared in a scope and a global otherwise.
+func (c *Compiler) classRef(stmt *token.ClassStmt) token.Expr {
+	ref := &token.VariableExpr{Name: *stmt.Name}
+	if c.current.scopeDepth > 0 {
+		c.locals[ref] = 0
+	}
+	return ref
+}
+
+func syntheticToken(tokenType scanner.TokenType, lexeme string, at scanner.Token) *scanner.Token {
+	at.TokenType = tokenType
+	at.Lexeme = &lexeme
+	return &at
+}
+
+func (c *Compiler) VisitExpressionStmt(stmt *token.ExpressionStmt) (interface{}, error) {
+	if _, err := c.compileExpr(stmt.Expression); err != nil {
+		return nil, err
+	}
+	c.emitOp(OP_POP, nil)
+	return nil, nil
+}
+
+func (c *Compiler) VisitFunctionStmt(stmt *token.FunctionStmt) (interface{}, error) {
+	if c.current.scopeDepth > 0 {
+		// Declare the local first, so the function can refer to itself.
+		c.addLocal(stmt.Name)
+		return nil, c.function(stmt, TYPE_FUNCTION)
+	}
+	if err := c.function(stmt, TYPE_FUNCTION); err != nil {
+		return nil, err
+	}
+	c.defineVariable(stmt.Name)
+	return nil, nil
+}
+
+func (c *Compiler) VisitIfStmt(stmt *token.IfStmt) (interface{}, error) {
+	if _, err := c.compileExpr(stmt.Condition); err != nil {
+		return nil, err
+	}
+	thenJump := c.emitJump(OP_JUMP_IF_FALSE, nil)
+	c.emitOp(OP_POP, nil)
+	if _, err := c.compileStmt(stmt.ThenBranch); err != nil {
+		return nil, err
+	}
+	elseJump := c.emitJump(OP_JUMP, nil)
+	c.patchJump(thenJump, nil)
+	c.emitOp(OP_POP, nil)
+	if stmt.ElseBranch != nil {
+		if _, err := c.compileStmt(stmt.ElseBranch); err != nil {
+			return nil, err
+		}
+	}
+	c.patchJump(elseJump, nil)
+	return nil, nil
+}
+
+func (c *Compiler) VisitPrintStmt(stmt *token.PrintStmt) (interface{}, error) {
+	if _, err := c.compileExpr(stmt.Expression); err != nil {
+		return nil, err
+	}
+	c.emitOp(OP_PRINT, nil)
+	return nil, nil
+}
+
+func (c *Compiler) VisitReturnStmt(stmt *token.ReturnStmt) (interface{}, error) {
+	if stmt.Value == nil {
+		c.emitDefaultReturnValue(stmt.Keyword)
+	} else if _, err := c.compileExpr(stmt.Value); err != nil {
+		return nil, err
+	}
+	if c.current.try != nil {
+		// The value waits in a hidden local while the finally clauses run.
+		c.beginScope()
+		slot := c.addHiddenLocal(stmt.Keyword)
+		if err := c.unwindTries(nil, stmt.Keyword); err != nil {
+			return nil, err
+		}
+		c.emit(stmt.Keyword, byte(OP_GET_LOCAL), byte(slot))
+		c.emitOp(OP_RETURN, stmt.Keyword)
+		c.endScope(stmt.Keyword)
+		return nil, nil
+	}
+	c.emitOp(OP_RETURN, stmt.Keyword)
+	return nil, nil
+}
+
+func (c *Compiler) VisitThrowStmt(stmt *token.ThrowStmt) (interface{}, error) {
+	if _, err := c.compileExpr(stmt.Value); err != nil {
+		return nil, err
+	}
+	c.emitOp(OP_THROW, stmt.Keyword)
+	return nil, nil
+}
+
+func (c *Compiler) VisitTryStmt(stmt *token.TryStmt) (interface{}, error) {
+	fc := c.current
+	enclosing := fc.try
+	defer func() {
+		fc.try = enclosing
+	}()
+
+	var finallyHandler int
+	if stmt.FinallyBody != nil {
+		finallyHandler = c.emitJump(OP_TRY_FINALLY, stmt.Keyword)
+		fc.try = &tryCompiler{enclosing: fc.try, loop: fc.loop, finally: stmt.FinallyBody}
+	}
+	if stmt.CatchName != nil {
+		catchHandler := c.emitJump(OP_TRY, stmt.Keyword)
+		fc.try = &tryCompiler{enclosing: fc.try, loop: fc.loop}
+		if err := c.compileBlock(stmt.Body); err != nil {
+			return nil, err
+		}
+		fc.try = fc.try.enclosing
+		c.emitOp(OP_POP_TRY, stmt.Keyword)
+		skipCatch := c.emitJump(OP_JUMP, stmt.Keyword)
+
+		// The handler pushes the caught value, it is the exception variable.
+		c.patchJump(catchHandler, stmt.Keyword)
+		c.beginScope()
+		c.addLocal(stmt.CatchName)
+		for _, s := range stmt.CatchBody {
+			if _, err := c.compileStmt(s); err != nil {
+				return nil, err
+			}
+		}
+		c.endScope(stmt.Keyword)
+		c.patchJump(skipCatch, stmt.Keyword)
+	} else if err := c.compileBlock(stmt.Body); err != nil {
+		return nil, err
+	}
+	if stmt.FinallyBody == nil {
+		return nil, nil
+	}
+
+	fc.try = enclosing
+	c.emitOp(OP_POP_TRY, stmt.Keyword)
+	if err := c.compileBlock(stmt.FinallyBody); err != nil {
+		return nil, err
+	}
+	end := c.emitJump(OP_JUMP, stmt.Keyword)
+
+	// The handler pushes the pending error, it is thrown again after the finally clause.
+	c.patchJump(finallyHandler, stmt.Keyword)
+	c.beginScope()
+	slot := c.addHiddenLocal(stmt.Keyword)
+	if err := c.compileBlock(stmt.FinallyBody); err != nil {
+		return nil, err
+	}
+	c.emit(stmt.Keyword, byte(OP_GET_LOCAL), byte(slot))
+	c.emitOp(OP_RETHROW, stmt.Keyword)
+	c.endScope(stmt.Keyword)
+	c.patchJump(end, stmt.Keyword)
+	return nil, nil
+}
+
+// addHiddenLocal turns the value on top of the stack into a local that no variable can refer to.
+func (c *Compiler) addHiddenLocal(tok *scanner.Token) int {
+	c.addLocal(syntheticToken(scanner.IDENTIFIER, "", *tok))
+	return len(c.current.locals) - 1
+}
+
+// unwindTries removes the handlers of the try statements left by a jump and runs their finally clauses,
+// it stops at the statements enclosing the loop, or unwinds all of them when the loop is nil.
+func (c *Compiler) unwindTries(loop *loopCompiler, tok *scanner.Token) error {
+	fc := c.current
+	enclosing := fc.try
+	defer func() {
+		fc.try = enclosing
+	}()
+	for t := enclosing; t != nil && (loop == nil || t.loop == loop); t = t.enclosing {
+		c.emitOp(OP_POP_TRY, tok)
+		if t.finally != nil {
+			fc.try = t.enclosing
+			if err := c.compileBlock(t.finally); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) VisitWhileStmt(stmt *token.WhileStmt) (interface{}, error) {
+	loopStart := len(c.chunk().Code)
+	if _, err := c.compileExpr(stmt.Condition); err != nil {
+		return nil, err
+	}
+	exitJump := c.emitJump(OP_JUMP_IF_FALSE, nil)
+	c.emitOp(OP_POP, nil)
+
+	loop := &loopCompiler{enclosing: c.current.loop, scopeDepth: c.current.scopeDepth}
+	c.current.loop = loop
+	_, err := c.compileStmt(stmt.Body)
+	c.current.loop = loop.enclosing
+	if err != nil {
+		return nil, err
+	}
+	for _, jump := range loop.continueJumps {
+		c.patchJump(jump, nil)
+	}
+	if stmt.Increment != nil {
+		if _, err := c.compileExpr(stmt.Increment); err != nil {
+			return nil, err
+		}
+		c.emitOp(OP_POP, nil)
+	}
+	c.emitLoop(loopStart, nil)
+	c.patchJump(exitJump, nil)
+	c.emitOp(OP_POP, nil)
+	// Break jumps past the pop of the condition, it is not on the stack when the body runs.
+	for _, jump := range loop.breakJumps {
+		c.patchJump(jump, nil)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitBreakStmt(stmt *token.BreakStmt) (interface{}, error) {
+	if err := c.unwindTries(c.current.loop, stmt.Keyword); err != nil {
+		return nil, err
+	}
+	c.discardLoopLocals(stmt.Keyword)
+	c.current.loop.breakJumps = append(c.current.loop.breakJumps, c.emitJump(OP_JUMP, stmt.Keyword))
+	return nil, nil
+}
+
+func (c *Compiler) VisitContinueStmt(stmt *token.ContinueStmt) (interface{}, error) {
+	if err := c.unwindTries(c.current.loop, stmt.Keyword); err != nil {
+		return nil, err
+	}
+	c.discardLoopLocals(stmt.Keyword)
+	c.current.loop.continueJumps = append(c.current.loop.continueJumps, c.emitJump(OP_JUMP, stmt.Keyword))
+	return nil, nil
+}
+
+// discardLoopLocals pops the locals declared inside the loop body before jumping out of it,
+// the compiler keeps tracking them for the rest of the body.
+func (c *Compiler) discardLoopLocals(tok *scanner.Token) {
+	fc := c.current
+	for idx := len(fc.locals) - 1; idx >= 0 && fc.locals[idx].depth > fc.loop.scopeDepth; idx-- {
+		if fc.locals[idx].isCaptured {
+			c.emitOp(OP_CLOSE_UPVALUE, tok)
+		} else {
+			c.emitOp(OP_POP, tok)
+		}
+	}
+}
+
+func (c *Compiler) VisitVarStmt(stmt *token.VarStmt) (interface{}, error) {
+	if stmt.Initializer != nil {
+		if _, err := c.compileExpr(stmt.Initializer); err != nil {
+			return nil, err
+		}
+	} else {
+		c.emitOp(OP_NIL, &stmt.Name)
+	}
+	c.defineVariable(&stmt.Name)
+	return nil, nil
+}
+
+func (c *Compiler) VisitAssignExpr(expr *token.AssignExpr) (interface{}, error) {
+	return nil, c.namedVariable(expr, &expr.Name, expr.Value)
+}
+
+func (c *Compiler) VisitLiteralExpr(expr *token.LiteralExpr) (interface{}, error) {
+	switch v := expr.Value.(type) {
+	case nil:
+		c.emitOp(OP_NIL, nil)
+	case bool:
+		if v {
+			c.emitOp(OP_TRUE, nil)
+		} else {
+			c.emitOp(OP_FALSE, nil)
+		}
+	default:
+		c.emitConstant(v, nil)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitLogicalExpr(expr *token.LogicalExpr) (interface{}, error) {
+	if _, err := c.compileExpr(expr.Left); err != nil {
+		return nil, err
+	}
+	if expr.Operator.TokenType == scanner.OR {
+		elseJump := c.emitJump(OP_JUMP_IF_FALSE, &expr.Operator)
+		endJump := c.emitJump(OP_JUMP, &expr.Operator)
+		c.patchJump(elseJump, &expr.Operator)
+		c.emitOp(OP_POP, &expr.Operator)
+		if _, err := c.compileExpr(expr.Right); err != nil {
+			return nil, err
+		}
+		c.patchJump(endJump, &expr.Operator)
+		return nil, nil
+	}
+	endJump := c.emitJump(OP_JUMP_IF_FALSE, &expr.Operator)
+	c.emitOp(OP_POP, &expr.Operator)
+	if _, err := c.compileExpr(expr.Right); err != nil {
+		return nil, err
+	}
+	c.patchJump(endJump, &expr.Operator)
+	return nil, nil
+}
+
+func (c *Compiler) VisitSetExpr(expr *token.SetExpr) (interface{}, error) {
+	if _, err := c.compileExpr(expr.Object); err != nil {
+		return nil, err
+	}
+	if _, err := c.compileExpr(expr.Value); err != nil {
+		return nil, err
+	}
+	c.emitShortOp(OP_SET_PROPERTY, c.makeConstant(*expr.Name.Lexeme, expr.Name), expr.Name)
+	return nil, nil
+}
+
+func (c *Compiler) VisitSuperExpr(expr *token.SuperExpr) (interface{}, error) {
+	this := &token.ThisExpr{Keyword: *syntheticToken(scanner.THIS, "this", expr.Keyword)}
+	c.locals[this] = 0
+	if err := c.namedVariable(this, &this.Keyword, nil); err != nil {
+		return nil, err
+	}
+	if err := c.namedVariable(expr, &expr.Keyword, nil); err != nil {
+		return nil, err
+	}
+	c.emitShortOp(OP_GET_SUPER, c.makeConstant(*expr.Method.Lexeme, &expr.Method), &expr.Method)
+	return nil, nil
+}
+
+func (c *Compiler) VisitThisExpr(expr *token.ThisExpr) (interface{}, error) {
+	return nil, c.namedVariable(expr, &expr.Keyword, nil)
+}
+
+func (c *Compiler) VisitUnaryExpr(expr *token.UnaryExpr) (interface{}, error) {
+	if _, err := c.compileExpr(expr.Right); err != nil {
+		return nil, err
+	}
+	switch expr.Operator.TokenType {
+	case scanner.BANG:
+		c.emitOp(OP_NOT, &expr.Operator)
+	case scanner.MINUS:
+		c.emitOp(OP_NEGATE, &expr.Operator)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitCallExpr(expr *token.CallExpr) (interface{}, error) {
+	if _, err := c.compileExpr(expr.Callee); err != nil {
+		return nil, err
+	}
+	for _, arg := range expr.Arguments {
+		if _, err := c.compileExpr(arg); err != nil {
+			return nil, err
+		}
+	}
+	c.emit(expr.Paren, byte(OP_CALL), byte(len(expr.Arguments)))
+	return nil, nil
+}
+
+func (c *Compiler) VisitGetExpr(expr *token.GetExpr) (interface{}, error) {
+	if _, err := c.compileExpr(expr.Object); err != nil {
+		return nil, err
+	}
+	c.emitShortOp(OP_GET_PROPERTY, c.makeConstant(*expr.Name.Lexeme, expr.Name), expr.Name)
+	return nil, nil
+}
+
+func (c *Compiler) VisitLambdaExpr(expr *token.LambdaExpr) (interface{}, error) {
+	return nil, c.function(expr.Function, TYPE_FUNCTION)
+}
+
+func (c *Compiler) VisitListExpr(expr *token.ListExpr) (interface{}, error) {
+	for _, element := range expr.Elements {
+		if _, err := c.compileExpr(element); err != nil {
+			return nil, err
+		}
+	}
+	if len(expr.Elements) > math.MaxUint16 {
+		c.error(expr.Bracket, "Too many elements in a list literal.")
+	}
+	c.emitShortOp(OP_LIST, len(expr.Elements), expr.Bracket)
+	return nil, nil
+}
+
+func (c *Compiler) VisitInterpolationExpr(expr *token.InterpolationExpr) (interface{}, error) {
+	for _, part := range expr.Parts {
+		if _, err := c.compileExpr(part); err != nil {
+			return nil, err
+		}
+	}
+	if len(expr.Parts) > math.MaxUint16 {
+		c.error(expr.Start, "Too many parts in an interpolated string.")
+	}
+	c.emitShortOp(OP_INTERPOLATE, len(expr.Parts), expr.Start)
+	return nil, nil
+}
+
+func (c *Compiler) VisitMapExpr(expr *token.MapExpr) (interface{}, error) {
+	for idx := range expr.Keys {
+		if _, err := c.compileExpr(expr.Keys[idx]); err != nil {
+			return nil, err
+		}
+		if _, err := c.compileExpr(expr.Values[idx]); err != nil {
+			return nil, err
+		}
+	}
+	if len(expr.Keys) > math.MaxUint16 {
+		c.error(expr.Brace, "Too many entries in a map literal.")
+	}
+	c.emitShortOp(OP_MAP, len(expr.Keys), expr.Brace)
+	return nil, nil
+}
+
+func (c *Compiler) VisitIndexExpr(expr *token.IndexExpr) (interface{}, error) {
+	if _, err := c.compileExpr(expr.Object); err != nil {
+		return nil, err
+	}
+	if _, err := c.compileExpr(expr.Index); err != nil {
+		return nil, err
+	}
+	c.emitOp(OP_GET_INDEX, expr.Bracket)
+	return nil, nil
+}
+
+func (c *Compiler) VisitIndexSetExpr(expr *token.IndexSetExpr) (interface{}, error) {
+	if _, err := c.compileExpr(expr.Object); err != nil {
+		return nil, err
+	}
+	if _, err := c.compileExpr(expr.Index); err != nil {
+		return nil, err
+	}
+	if _, err := c.compileExpr(expr.Value); err != nil {
+		return nil, err
+	}
+	c.emitOp(OP_SET_INDEX, expr.Bracket)
+	return nil, nil
+}
+
+func (c *Compiler) VisitVariableExpr(expr *token.VariableExpr) (interface{}, error) {
+	return nil, c.namedVariable(expr, &expr.Name, nil)
+}
+
+var binaryOps = map[scanner.TokenType]OpCode{
+	scanner.BANG_EQUAL:    OP_NOT_EQUAL,
+	scanner.EQUAL_EQUAL:   OP_EQUAL,
+	scanner.GREATER:       OP_GREATER,
+	scanner.GREATER_EQUAL: OP_GREATER_EQUAL,
+	scanner.LESS:          OP_LESS,
+	scanner.LESS_EQUAL:    OP_LESS_EQUAL,
+	scanner.PLUS:          OP_ADD,
+	scanner.MINUS:         OP_SUBTRACT,
+	scanner.STAR:          OP_MULTIPLY,
+	scanner.SLASH:         OP_DIVIDE,
+}
+
+func (c *Compiler) VisitBinaryExpr(expr *token.BinaryExpr) (interface{}, error) {
+	if _, err := c.compileExpr(expr.Left); err != nil {
+		return nil, err
+	}
+	if _, err := c.compileExpr(expr.Right); err != nil {
+		return nil, err
+	}
+	op, ok := binaryOps[expr.Operator.TokenType]
+	if !ok {
+		c.error(&expr.Operator, "Unknown binary operator.")
+		return nil, nil
+	}
+	c.emitOp(op, &expr.Operator)
+	return nil, nil
+}
+
+func (c *Compiler) VisitGroupingExpr(expr *token.GroupingExpr) (interface{}, error) {
+	return c.compileExpr(expr.Expression)
+}
+
+type ErrorCallback = func(scanner.Token, string)
@@ -0,0 +1,87 @@
+package vm
+
+import (
+	"fmt"
+	"github.com/nesyuk/golox/interpreter"
+	"github.com/nesyuk/golox/scanner"
+)
+
+// Function is a compiled function, the top-level script is a function with an empty name.
+type Function struct {
+	name         string
+	arity        int
+	upvalueCount int
+	chunk        Chunk
+	anonymous    bool
+	// Getters are called when the property is accessed.
+	getter bool
+}
+
+func (fn *Function) String() string {
+	if fn.name == "" {
+		return "<script>"
+	}
+	if fn.anonymous {
+		return "<anonymous fn.>"
+	}
+	return fmt.Sprintf("<fn '%v'.>", fn.name)
+}
+
+type closure struct {
+	fn       *Function
+	upvalues []*upvalue
+	// Globals of the module the closure was created in.
+	globals map[string]interface{}
+}
+
+func (c *closure) String() string {
+	return c.fn.String()
+}
+
+// upvalue points to a variable on the stack while it is open and owns the value after it has been closed.
+type upvalue struct {
+	location *interface{}
+	slot     int
+	closed   interface{}
+	next     *upvalue
+}
+
+type class struct {
+	name         string
+	methods      map[string]*closure
+	classMethods map[string]*closure
+	fields       map[string]interface{}
+}
+
+// get returns a class field or a class method.
+func (c *class) get(name string, tok *scanner.Token) (interface{}, error) {
+	if value, exist := c.fields[name]; exist {
+		return value, nil
+	}
+	if method, exist := c.classMethods[name]; exist {
+		return method, nil
+	}
+	return nil, &interpreter.RuntimeError{Token: tok, Message: fmt.Sprintf("Undefined property '%v'.", name)}
+}
+
+func (c *class) String() string {
+	return fmt.Sprintf("<class '%v'.>", c.name)
+}
+
+type instance struct {
+	class  *class
+	fields map[string]interface{}
+}
+
+func (i *instance) String() string {
+	return fmt.Sprintf("<'%v' instance.>", i.class.name)
+}
+
+type boundMethod struct {
+	receiver interface{}
+	method   *closure
+}
+
+func (b *boundMethod) String() string {
+	return b.method.String()
+}
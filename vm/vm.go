@@ -0,0 +1,672 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nesyuk/golox/interpreter"
+	"github.com/nesyuk/golox/scanner"
+	"reflect"
+	"strings"
+)
+
+var interpreterType = reflect.TypeOf((*interpreter.Interpreter)(nil))
+
+const (
+	framesMax = 1024
+	stackMax  = framesMax * maxLocals
+)
+
+type callFrame struct {
+	closure *closure
+	ip      int
+	// Index of the first stack slot of the frame.
+	base int
+	// Canonical path of the module whose top-level code runs in the frame.
+	module string
+}
+
+// handler is an installed exception handler of a try statement.
+type handler struct {
+	frameCount int
+	sp         int
+	ip         int
+	// Handlers of finally clauses receive the error itself to throw it again.
+	finally bool
+}
+
+// VM executes compiled functions, it produces the same output and runtime errors as interpreter.Interpreter.
+type VM struct {
+	frames        [framesMax]callFrame
+	frameCount    int
+	stack         []interface{}
+	sp            int
+	globals       map[string]interface{}
+	natives       map[string]interpreter.LoxCallable
+	openUpvalues  *upvalue
+	handlers      []handler
+	errorCallback interpreter.ErrorCallback
+	printCallback interpreter.PrintCallback
+	loader        ModuleLoader
+	// Path of the program being run.
+	filename string
+	// Imported modules by their canonical path.
+	modules map[string]interface{}
+}
+
+// ModuleLoader reads, parses, resolves and compiles an imported file, the path is already canonical.
+type ModuleLoader func(path string) (*Function, error)
+
+func New(onError interpreter.ErrorCallback, onPrint interpreter.PrintCallback) *VM {
+	vm := &VM{
+		stack:         make([]interface{}, stackMax),
+		natives:       interpreter.Natives(),
+		errorCallback: onError,
+		printCallback: onPrint,
+		modules:       make(map[string]interface{}),
+	}
+	vm.globals = vm.newGlobals()
+	return vm
+}
+
+// SetModuleLoader enables import statements, filename is the path of the program being run.
+// Imports of a program without a file are relative to the working directory.
+func (vm *VM) SetModuleLoader(filename string, loader ModuleLoader) {
+	if filename != "" {
+		filename = interpreter.ModulePath("", filename)
+	}
+	vm.loader = loader
+	vm.filename = filename
+}
+
+// RegisterFunc binds a Go function as a global Lox function, see interpreter.Interpreter.RegisterFunc.
+// The VM has no tree-walking interpreter to pass in, functions taking an *interpreter.Interpreter are rejected.
+func (vm *VM) RegisterFunc(name string, fn interface{}) error {
+	if t := reflect.TypeOf(fn); t != nil && t.Kind() == reflect.Func && t.NumIn() > 0 && t.In(0) == interpreterType {
+		return fmt.Errorf("native '%v' can't take an *interpreter.Interpreter on the bytecode VM", name)
+	}
+	native, err := interpreter.NewNative(name, fn)
+	if err != nil {
+		return err
+	}
+	vm.globals[name] = native
+	return nil
+}
+
+// GlobalNames returns the names of the defined global variables.
+func (vm *VM) GlobalNames() []string {
+	names := make([]string, 0, len(vm.globals))
+	for name := range vm.globals {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (vm *VM) newGlobals() map[string]interface{} {
+	globals := make(map[string]interface{}, len(vm.natives))
+	for name, native := range vm.natives {
+		globals[name] = native
+	}
+	return globals
+}
+
+func (vm *VM) Interpret(fn *Function) error {
+	cl := &closure{fn: fn, globals: vm.globals}
+	vm.push(cl)
+	err := vm.call(cl, 0, nil)
+	if err == nil {
+		err = vm.run()
+	}
+	err = interpreter.Uncaught(err)
+	var runtimeErr *interpreter.RuntimeError
+	if err != nil && errors.As(err, &runtimeErr) {
+		vm.resetStack()
+		vm.errorCallback(runtimeErr)
+		return nil
+	}
+	return err
+}
+
+func (vm *VM) resetStack() {
+	for i := 0; i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = 0
+	vm.frameCount = 0
+	vm.openUpvalues = nil
+	vm.handlers = nil
+}
+
+// errStackOverflow is raised by push when the stack is full, execute reports it as a runtime error.
+var errStackOverflow = errors.New("stack overflow")
+
+func (vm *VM) push(value interface{}) {
+	if vm.sp == len(vm.stack) {
+		panic(errStackOverflow)
+	}
+	vm.stack[vm.sp] = value
+	vm.sp++
+}
+
+func (vm *VM) pop() interface{} {
+	vm.sp--
+	value := vm.stack[vm.sp]
+	vm.stack[vm.sp] = nil
+	return value
+}
+
+func (vm *VM) peek(distance int) interface{} {
+	return vm.stack[vm.sp-1-distance]
+}
+
+func (vm *VM) run() error {
+	for {
+		err := vm.execute()
+		if err == nil || !vm.catch(err) {
+			return err
+		}
+	}
+}
+
+// catch unwinds the stack to the innermost exception handler and jumps to it.
+func (vm *VM) catch(err error) bool {
+	value, catchable := interpreter.Catchable(err)
+	if !catchable || len(vm.handlers) == 0 {
+		return false
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.closeUpvalues(h.sp)
+	for vm.sp > h.sp {
+		vm.pop()
+	}
+	vm.frameCount = h.frameCount
+	vm.frames[vm.frameCount-1].ip = h.ip
+	if h.finally {
+		vm.push(err)
+	} else {
+		vm.push(value)
+	}
+	return true
+}
+
+func (vm *VM) execute() (err error) {
+	frame := &vm.frames[vm.frameCount-1]
+	chunk := &frame.closure.fn.chunk
+
+	readByte := func() byte {
+		b := chunk.Code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() int {
+		frame.ip += 2
+		return int(chunk.Code[frame.ip-2])<<8 | int(chunk.Code[frame.ip-1])
+	}
+	readString := func() string {
+		return chunk.Constants[readShort()].(string)
+	}
+	// Token of the instruction being executed.
+	current := func() *scanner.Token {
+		return chunk.Tokens[frame.ip-1]
+	}
+	// Temporaries can fill the stack before the frames run out.
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errStackOverflow {
+				panic(r)
+			}
+			err = &interpreter.RuntimeError{Token: current(), Message: "Stack overflow."}
+		}
+	}()
+
+	for {
+		switch OpCode(readByte()) {
+		case OP_CONSTANT:
+			vm.push(chunk.Constants[readShort()])
+		case OP_NIL:
+			vm.push(nil)
+		case OP_TRUE:
+			vm.push(true)
+		case OP_FALSE:
+			vm.push(false)
+		case OP_POP:
+			vm.pop()
+		case OP_GET_LOCAL:
+			vm.push(vm.stack[frame.base+int(readByte())])
+		case OP_SET_LOCAL:
+			vm.stack[frame.base+int(readByte())] = vm.peek(0)
+		case OP_GET_GLOBAL:
+			name := readString()
+			value, exist := frame.closure.globals[name]
+			if !exist {
+				return &interpreter.RuntimeError{Token: current(), Message: fmt.Sprintf("Undefined variable '%v'.", name)}
+			}
+			vm.push(value)
+		case OP_DEFINE_GLOBAL:
+			frame.closure.globals[readString()] = vm.pop()
+		case OP_SET_GLOBAL:
+			name := readString()
+			if _, exist := frame.closure.globals[name]; !exist {
+				return &interpreter.RuntimeError{Token: current(), Message: fmt.Sprintf("Undefined variable '%v'.", name)}
+			}
+			frame.closure.globals[name] = vm.peek(0)
+		case OP_GET_UPVALUE:
+			vm.push(*frame.closure.upvalues[readByte()].location)
+		case OP_SET_UPVALUE:
+			*frame.closure.upvalues[readByte()].location = vm.peek(0)
+		case OP_GET_PROPERTY:
+			name := readString()
+			if cls, isClass := vm.peek(0).(*class); isClass {
+				value, err := cls.get(name, current())
+				if err != nil {
+					return err
+				}
+				if method, ok := value.(*closure); ok && method.fn.getter {
+					// The class stays on the stack in the getter's slot zero.
+					if err := vm.call(method, 0, current()); err != nil {
+						return err
+					}
+					frame = &vm.frames[vm.frameCount-1]
+					chunk = &frame.closure.fn.chunk
+					break
+				}
+				vm.pop()
+				vm.push(value)
+				break
+			}
+			inst, ok := vm.peek(0).(*instance)
+			if obj, isObject := vm.peek(0).(interpreter.Object); !ok && isObject {
+				// Built-in values like lists, their methods are natives.
+				value, err := obj.Get(current())
+				if err != nil {
+					return err
+				}
+				vm.pop()
+				vm.push(value)
+				break
+			}
+			if !ok {
+				return &interpreter.RuntimeError{Token: current(), Message: "Only instances have properties."}
+			}
+			if value, exist := inst.fields[name]; exist {
+				vm.pop()
+				vm.push(value)
+				break
+			}
+			if err := vm.bindMethod(inst.class, name, current()); err != nil {
+				return err
+			}
+			frame = &vm.frames[vm.frameCount-1]
+			chunk = &frame.closure.fn.chunk
+		case OP_SET_PROPERTY:
+			name := readString()
+			var fields map[string]interface{}
+			switch obj := vm.peek(1).(type) {
+			case *instance:
+				fields = obj.fields
+			case *class:
+				fields = obj.fields
+			default:
+				return &interpreter.RuntimeError{Token: current(), Message: "Only instances have fields."}
+			}
+			fields[name] = vm.peek(0)
+			value := vm.pop()
+			vm.pop()
+			vm.push(value)
+		case OP_INTERPOLATE:
+			count := readShort()
+			var sb strings.Builder
+			for _, value := range vm.stack[vm.sp-count : vm.sp] {
+				sb.WriteString(interpreter.Stringify(value))
+			}
+			for i := 0; i < count; i++ {
+				vm.pop()
+			}
+			vm.push(sb.String())
+		case OP_LIST:
+			count := readShort()
+			elements := make([]interface{}, count)
+			copy(elements, vm.stack[vm.sp-count:vm.sp])
+			for i := 0; i < count; i++ {
+				vm.pop()
+			}
+			vm.push(interpreter.NewList(elements))
+		case OP_MAP:
+			count := readShort()
+			keys, values := make([]interface{}, count), make([]interface{}, count)
+			for i := count - 1; i >= 0; i-- {
+				values[i], keys[i] = vm.pop(), vm.pop()
+			}
+			m, err := interpreter.NewMap(current(), keys, values)
+			if err != nil {
+				return err
+			}
+			vm.push(m)
+		case OP_GET_INDEX:
+			index, object := vm.pop(), vm.pop()
+			value, err := interpreter.GetIndex(object, current(), index)
+			if err != nil {
+				return err
+			}
+			vm.push(value)
+		case OP_SET_INDEX:
+			value, index, object := vm.pop(), vm.pop(), vm.pop()
+			if err := interpreter.SetIndex(object, current(), index, value); err != nil {
+				return err
+			}
+			vm.push(value)
+		case OP_TRY, OP_TRY_FINALLY:
+			op := OpCode(chunk.Code[frame.ip-1])
+			offset := readShort()
+			vm.handlers = append(vm.handlers, handler{vm.frameCount, vm.sp, frame.ip + offset, op == OP_TRY_FINALLY})
+		case OP_POP_TRY:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case OP_THROW:
+			return &interpreter.ThrowException{Value: vm.pop(), Token: current()}
+		case OP_RETHROW:
+			return vm.pop().(error)
+		case OP_GET_SUPER:
+			name := readString()
+			superclass := vm.pop().(*class)
+			if err := vm.bindMethod(superclass, name, current()); err != nil {
+				return err
+			}
+			frame = &vm.frames[vm.frameCount-1]
+			chunk = &frame.closure.fn.chunk
+		case OP_EQUAL:
+			right, left := vm.pop(), vm.pop()
+			vm.push(isEqual(left, right))
+		case OP_NOT_EQUAL:
+			right, left := vm.pop(), vm.pop()
+			vm.push(!isEqual(left, right))
+		case OP_GREATER, OP_GREATER_EQUAL, OP_LESS, OP_LESS_EQUAL, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE:
+			op := OpCode(chunk.Code[frame.ip-1])
+			l, lok := vm.peek(1).(float64)
+			r, rok := vm.peek(0).(float64)
+			if !lok || !rok {
+				return &interpreter.RuntimeError{Token: current(), Message: "Operands must be a numbers."}
+			}
+			vm.pop()
+			vm.pop()
+			vm.push(numberOp(op, l, r))
+		case OP_ADD:
+			right, left := vm.pop(), vm.pop()
+			sum, err := interpreter.Add(current(), left, right)
+			if err != nil {
+				return err
+			}
+			vm.push(sum)
+		case OP_NOT:
+			vm.push(!isTruthy(vm.pop()))
+		case OP_NEGATE:
+			value, ok := vm.peek(0).(float64)
+			if !ok {
+				return &interpreter.RuntimeError{Token: current(), Message: "Operand must be a number."}
+			}
+			vm.pop()
+			vm.push(-value)
+		case OP_PRINT:
+			vm.printCallback(interpreter.Stringify(vm.pop()))
+		case OP_JUMP:
+			offset := readShort()
+			frame.ip += offset
+		case OP_JUMP_IF_FALSE:
+			offset := readShort()
+			if !isTruthy(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case OP_LOOP:
+			offset := readShort()
+			frame.ip -= offset
+		case OP_CALL:
+			argCount := int(readByte())
+			if err := vm.callValue(vm.peek(argCount), argCount, current()); err != nil {
+				return err
+			}
+			frame = &vm.frames[vm.frameCount-1]
+			chunk = &frame.closure.fn.chunk
+		case OP_CLOSURE:
+			fn := chunk.Constants[readShort()].(*Function)
+			cl := &closure{fn: fn, upvalues: make([]*upvalue, fn.upvalueCount), globals: frame.closure.globals}
+			for i := range cl.upvalues {
+				isLocal := readByte()
+				index := int(readByte())
+				if isLocal == 1 {
+					cl.upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					cl.upvalues[i] = frame.closure.upvalues[index]
+				}
+			}
+			vm.push(cl)
+		case OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(vm.sp - 1)
+			vm.pop()
+		case OP_RETURN:
+			result := vm.pop()
+			if frame.module != "" {
+				// The value of an import is the namespace of the module.
+				result = interpreter.NewModule(frame.module, frame.closure.globals, vm.natives)
+				vm.modules[frame.module] = result
+			}
+			vm.closeUpvalues(frame.base)
+			vm.frameCount--
+			for vm.sp > frame.base {
+				vm.pop()
+			}
+			if vm.frameCount == 0 {
+				return nil
+			}
+			vm.push(result)
+			frame = &vm.frames[vm.frameCount-1]
+			chunk = &frame.closure.fn.chunk
+		case OP_IMPORT:
+			if err := vm.importModule(readString(), current()); err != nil {
+				return err
+			}
+			frame = &vm.frames[vm.frameCount-1]
+			chunk = &frame.closure.fn.chunk
+		case OP_CLASS:
+			vm.push(&class{
+				name:         readString(),
+				methods:      make(map[string]*closure),
+				classMethods: make(map[string]*closure),
+				fields:       make(map[string]interface{}),
+			})
+		case OP_INHERIT:
+			superclass, ok := vm.peek(1).(*class)
+			if !ok {
+				return &interpreter.RuntimeError{Token: current(), Message: "Superclass must be a class."}
+			}
+			subclass := vm.peek(0).(*class)
+			for name, method := range superclass.methods {
+				subclass.methods[name] = method
+			}
+			for name, method := range superclass.classMethods {
+				subclass.classMethods[name] = method
+			}
+			vm.pop()
+		case OP_METHOD:
+			name := readString()
+			method := vm.pop().(*closure)
+			vm.peek(0).(*class).methods[name] = method
+		case OP_CLASS_METHOD:
+			name := readString()
+			method := vm.pop().(*closure)
+			vm.peek(0).(*class).classMethods[name] = method
+		default:
+			return fmt.Errorf("unknown opcode %d", chunk.Code[frame.ip-1])
+		}
+	}
+}
+
+func (vm *VM) callValue(callee interface{}, argCount int, tok *scanner.Token) error {
+	switch c := callee.(type) {
+	case *closure:
+		return vm.call(c, argCount, tok)
+	case *boundMethod:
+		vm.stack[vm.sp-argCount-1] = c.receiver
+		return vm.call(c.method, argCount, tok)
+	case *class:
+		vm.stack[vm.sp-argCount-1] = &instance{class: c, fields: make(map[string]interface{})}
+		if initializer, exist := c.methods["init"]; exist {
+			return vm.call(initializer, argCount, tok)
+		}
+		if argCount != 0 {
+			return &interpreter.RuntimeError{Token: tok, Message: fmt.Sprintf("Expected %d arguments but got %d.", 0, argCount)}
+		}
+		return nil
+	case interpreter.LoxCallable:
+		// Natives are shared with the tree-walking interpreter, they don't need one to run.
+		if message := interpreter.ArityError(c, argCount); message != "" {
+			return &interpreter.RuntimeError{Token: tok, Message: message}
+		}
+		args := make([]interface{}, argCount)
+		copy(args, vm.stack[vm.sp-argCount:vm.sp])
+		result, err := c.Call(nil, args)
+		if err != nil {
+			return &interpreter.RuntimeError{Token: tok, Message: err.Error()}
+		}
+		for i := 0; i <= argCount; i++ {
+			vm.pop()
+		}
+		vm.push(result)
+		return nil
+	}
+	return &interpreter.RuntimeError{Token: tok, Message: "Can only call functions and classes."}
+}
+
+func (vm *VM) call(cl *closure, argCount int, tok *scanner.Token) error {
+	if argCount != cl.fn.arity {
+		return &interpreter.RuntimeError{Token: tok, Message: fmt.Sprintf("Expected %d arguments but got %d.", cl.fn.arity, argCount)}
+	}
+	if vm.frameCount == framesMax {
+		return &interpreter.RuntimeError{Token: tok, Message: "Stack overflow."}
+	}
+	vm.frames[vm.frameCount] = callFrame{closure: cl, base: vm.sp - argCount - 1}
+	vm.frameCount++
+	return nil
+}
+
+func (vm *VM) bindMethod(cls *class, name string, tok *scanner.Token) error {
+	method, exist := cls.methods[name]
+	if !exist {
+		return &interpreter.RuntimeError{Token: tok, Message: fmt.Sprintf("Undefined property '%v'.", name)}
+	}
+	if method.fn.getter {
+		// The receiver stays on the stack in the getter's slot zero.
+		return vm.call(method, 0, tok)
+	}
+	bound := &boundMethod{receiver: vm.peek(0), method: method}
+	vm.pop()
+	vm.push(bound)
+	return nil
+}
+
+// importModule calls the top-level code of a module the first time it is imported,
+// the namespace of the module is on the stack once it returns.
+func (vm *VM) importModule(importPath string, tok *scanner.Token) error {
+	if vm.loader == nil {
+		return &interpreter.RuntimeError{Token: tok, Message: "Can't import modules here."}
+	}
+	importing := []string{vm.filename}
+	for i := 0; i < vm.frameCount; i++ {
+		if vm.frames[i].module != "" {
+			importing = append(importing, vm.frames[i].module)
+		}
+	}
+	path := interpreter.ModulePath(importing[len(importing)-1], importPath)
+	if module, exist := vm.modules[path]; exist {
+		vm.push(module)
+		return nil
+	}
+	for _, p := range importing {
+		if p == path {
+			return &interpreter.RuntimeError{Token: tok, Message: interpreter.ImportCycle(importing, path)}
+		}
+	}
+	fn, err := vm.loader(path)
+	if err != nil {
+		return &interpreter.RuntimeError{Token: tok, Message: fmt.Sprintf("Can't import '%v': %v.", importPath, err)}
+	}
+	cl := &closure{fn: fn, globals: vm.newGlobals()}
+	vm.push(cl)
+	if err := vm.call(cl, 0, tok); err != nil {
+		return err
+	}
+	vm.frames[vm.frameCount-1].module = path
+	return nil
+}
+
+func (vm *VM) captureUpvalue(slot int) *upvalue {
+	var prev *upvalue
+	up := vm.openUpvalues
+	for up != nil && up.slot > slot {
+		prev = up
+		up = up.next
+	}
+	if up != nil && up.slot == slot {
+		return up
+	}
+	created := &upvalue{location: &vm.stack[slot], slot: slot, next: up}
+	if prev == nil {
+		vm.openUpvalues = created
+	} else {
+		prev.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves every variable captured at or above the slot off the stack.
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		up := vm.openUpvalues
+		up.closed = *up.location
+		up.location = &up.closed
+		vm.openUpvalues = up.next
+	}
+}
+
+func numberOp(op OpCode, l, r float64) interface{} {
+	switch op {
+	case OP_GREATER:
+		return l > r
+	case OP_GREATER_EQUAL:
+		return l >= r
+	case OP_LESS:
+		return l < r
+	case OP_LESS_EQUAL:
+		return l <= r
+	case OP_SUBTRACT:
+		return l - r
+	case OP_MULTIPLY:
+		return l * r
+	}
+	return l / r
+}
+
+func isTruthy(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if val, isBool := value.(bool); isBool {
+		return val
+	}
+	return true
+}
+
+func isEqual(left, right interface{}) bool {
+	switch v1 := left.(type) {
+	case nil:
+		return right == nil
+	case bool:
+		v2, ok := right.(bool)
+		return ok && v1 == v2
+	case float64:
+		v2, ok := right.(float64)
+		return ok && v1 == v2
+	case string:
+		v2, ok := right.(string)
+		return ok && v1 == v2
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package vm
+
+import (
+	"fmt"
+	"github.com/nesyuk/golox/interpreter"
+	"github.com/nesyuk/golox/parser"
+	"github.com/nesyuk/golox/resolver"
+	"github.com/nesyuk/golox/scanner"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestInterpret(t *testing.T) {
+	tests := []struct {
+		source       string
+		expect       []string
+		runtimeError string
+	}{
+		{"print 1 + 2 * 3;", []string{"7"}, ""},
+		{"print \"a\" + \"b\";", []string{"ab"}, ""},
+		{"print !nil; print 1 == 1; print 1 != \"1\"; print 2 >= 2; print 1 < 0;", []string{"true", "true", "true", "true", "false"}, ""},
+		{"print nil or \"x\"; print false and 1;", []string{"x", "false"}, ""},
+		{"var a = 1; a = a + 1; print a;", []string{"2"}, ""},
+		{"var a = 1; { var a = 2; { var b = a; print b; } } print a;", []string{"2", "1"}, ""},
+		{"if (1 > 2) print \"then\"; else print \"else\";", []string{"else"}, ""},
+		{"var i = 0; while (i < 3) { print i; i = i + 1; }", []string{"0", "1", "2"}, ""},
+		{"for (var i = 0; i < 2; i = i + 1) print i;", []string{"0", "1"}, ""},
+		{"fun add(a, b) { return a + b; } print add(1, 2); print add;", []string{"3", "<fn 'add'.>"}, ""},
+		{"fun fib(n) { if (n <= 1) return n; return fib(n - 2) + fib(n - 1); } print fib(15);", []string{"610"}, ""},
+		{"fun makeCounter() { var i = 0; fun count() { i = i + 1; return i; } return count; }\nvar c = makeCounter(); c(); print c();", []string{"2"}, ""},
+		{"var fns; { var a = \"outer\"; fun f() { return a; } fns = f; a = \"changed\"; } print fns();", []string{"changed"}, ""},
+		{"fun outer() { var x = 1; fun middle() { fun inner() { return x; } return inner; } return middle()(); } print outer();", []string{"1"}, ""},
+		{"class A { init(v) { this.v = v; } get() { return this.v; } }\nvar a = A(3); print a.get(); print a; print A;", []string{"3", "<'A' instance.>", "<class 'A'.>"}, ""},
+		{"class A { init() { this.x = 1; return; } }\nprint A().x;", []string{"1"}, ""},
+		{"class A { hi() { return \"A\"; } }\nclass B < A { hi() { return super.hi() + \"B\"; } }\nprint B().hi();", []string{"AB"}, ""},
+		{"class A { m() { return this; } }\nvar m = A().m; print m();", []string{"<'A' instance.>"}, ""},
+		{"{ class Local { m() { return Local; } } print Local().m(); }", []string{"<class 'Local'.>"}, ""},
+		{"print later; var later = 1;", []string{}, "Undefined variable 'later'."},
+		{"print -\"a\";", []string{}, "Operand must be a number."},
+		{"print 1 + true;", []string{}, "Operands must be numbers: true"},
+		{"print 1 < \"a\";", []string{}, "Operands must be a numbers."},
+		{"later = 1; var later;", []string{}, "Undefined variable 'later'."},
+		{"fun f(a) {} f();", []string{}, "Expected 1 arguments but got 0."},
+		{"\"str\"();", []string{}, "Can only call functions and classes."},
+		{"print 1; var a = 1; a.b;", []string{"1"}, "Only instances have properties."},
+		{"class A {} A().b;", []string{}, "Undefined property 'b'."},
+		{"var NotClass = 1; class A < NotClass {}", []string{}, "Superclass must be a class."},
+		{"fun f() { f(); } f();", []string{}, "Stack overflow."},
+	}
+	for _, test := range tests {
+		got := make([]string, 0)
+		errs := make([]string, 0)
+		runtimeErrors := make([]string, 0)
+		onError := func(tok scanner.Token, message string) {
+			errs = append(errs, message)
+		}
+
+		tokens := scanner.NewScanner(test.source, func(line int, column int, message string) {
+			errs = append(errs, message)
+		}).ScanTokens()
+		stmts, _ := parser.NewParser(tokens, onError).Parse()
+		compiler := NewCompiler(onError)
+		resolver.New(compiler, onError).Resolve(stmts)
+		fn, err := compiler.Compile(stmts)
+		if err != nil || len(errs) != 0 {
+			t.Fatalf("unexpected errors: %v %v (in %v)", err, errs, test.source)
+		}
+		machine := New(func(err *interpreter.RuntimeError) {
+			runtimeErrors = append(runtimeErrors, err.Error())
+		}, func(s string) {
+			got = append(got, s)
+		})
+		if err = machine.Interpret(fn); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != len(test.expect) {
+			t.Fatalf("expect %v, got: %v (in %v)", test.expect, got, test.source)
+		}
+		for i := range got {
+			if got[i] != test.expect[i] {
+				t.Errorf("expect: %v, got: %v (in %v)", test.expect[i], got[i], test.source)
+			}
+		}
+		if test.runtimeError == "" && len(runtimeErrors) != 0 || test.runtimeError != "" && (len(runtimeErrors) != 1 || runtimeErrors[0] != test.runtimeError) {
+			t.Errorf("expect runtime error: '%v', got: %v (in %v)", test.runtimeError, runtimeErrors, test.source)
+		}
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	got := make([]string, 0)
+	machine := New(func(err *interpreter.RuntimeError) {
+		t.Errorf("unexpected runtime error: %v", err)
+	}, func(s string) {
+		got = append(got, s)
+	})
+	if err := machine.RegisterFunc("double", func(n int) int { return n * 2 }); err != nil {
+		t.Fatal(err)
+	}
+	if err := machine.RegisterFunc("apply", func(i *interpreter.Interpreter, fn interpreter.LoxCallable) (interface{}, error) {
+		return i.Call(fn, []interface{}{})
+	}); err == nil {
+		t.Errorf("expect functions taking the interpreter to be rejected")
+	}
+
+	onError := func(tok scanner.Token, message string) {
+		t.Errorf("unexpected error: %v", message)
+	}
+	stmts, _ := parser.NewParser(scanner.NewScanner("print double(21);", nil).ScanTokens(), onError).Parse()
+	compiler := NewCompiler(onError)
+	res := resolver.New(compiler, onError)
+	res.DeclareGlobals(machine.GlobalNames()...)
+	res.Resolve(stmts)
+	fn, err := compiler.Compile(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = machine.Interpret(fn); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "42" {
+		t.Errorf("expect [42], got: %v", got)
+	}
+}
+
+// Each call keeps its arguments on the stack, with many parameters the stack fills up before the frames.
+func TestStackOverflow(t *testing.T) {
+	params := make([]string, 255)
+	for i := range params {
+		params[i] = fmt.Sprintf("p%d", i)
+	}
+	source := fmt.Sprintf("fun f(%[1]v) { return f(%[1]v); }\nf(%[2]v1);", strings.Join(params, ", "), strings.Repeat("1, ", 254))
+
+	runtimeErrors := make([]string, 0)
+	onError := func(tok scanner.Token, message string) {
+		t.Errorf("unexpected error: %v", message)
+	}
+	stmts, _ := parser.NewParser(scanner.NewScanner(source, nil).ScanTokens(), onError).Parse()
+	compiler := NewCompiler(onError)
+	resolver.New(compiler, onError).Resolve(stmts)
+	fn, err := compiler.Compile(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := New(func(err *interpreter.RuntimeError) {
+		runtimeErrors = append(runtimeErrors, err.Error())
+	}, func(string) {})
+	if err = machine.Interpret(fn); err != nil {
+		t.Fatal(err)
+	}
+	if len(runtimeErrors) != 1 || runtimeErrors[0] != "Stack overflow." {
+		t.Errorf("expect a stack overflow, got: %v", runtimeErrors)
+	}
+}
+
+func TestAddConstant(t *testing.T) {
+	c := &Chunk{}
+	nan, negativeZero := math.NaN(), math.Copysign(0, -1)
+	for _, value := range []interface{}{1.0, "a", nan, 0.0, negativeZero, []int{1}, 1.0, "a", nan, []int{1}} {
+		c.addConstant(value)
+	}
+	if len(c.Constants) != 7 {
+		t.Errorf("expect numbers and strings to be added once, got: %v", c.Constants)
+	}
+	if c.addConstant(negativeZero) != 4 || c.addConstant(0.0) != 3 {
+		t.Errorf("expect -0 to be kept apart from 0")
+	}
+}